@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"envme/lib/tui"
+	"envme/lib/utils"
+	"envme/pkg/envme"
+	"fmt"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	rootCmd.AddCommand(addCmd)
+
+	// Add flags to the `envme add` command
+	addCmd.Flags().StringArrayP("env", "e", []string{}, "Add environment variables for the service")
+	addCmd.Flags().StringArray("env-file", []string{}, "Read in a file of environment variables, --env takes precedence")
+	addCmd.Flags().StringArrayP("volume", "v", []string{}, "Mount a volume (format: [<source>:]<target>[:ro|rw])")
+	addCmd.Flags().StringArray("publish", []string{}, "Publish a port on the host, a free host port is assigned when omitted (format: [<host-port>:]<container-port>)")
+}
+
+// addCmd handles the `envme add` command
+var addCmd = &cobra.Command{
+	Use:   "add <stack-name> <service-name> <image-name>",
+	Short: "Add a service to an existing stack",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 3 && !cmd.Flags().Changed("interactive") {
+			return fmt.Errorf("\n  Please specify <stack-name>, <service-name> and <image-name> or using interactive mode\n")
+		}
+		return nil
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// The keys are shared with `envme create`, bind them only when add runs
+		for _, name := range []string{"env", "env-file", "volume", "publish"} {
+			if err := viper.BindPFlag(name, cmd.Flags().Lookup(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var stack, service, image string
+		network := viper.GetString("network")
+		if len(args) < 3 && cmd.Flags().Changed("interactive") {
+			_, err := tea.NewProgram(tui.NewAddServiceForm()).Run()
+			if err != nil {
+				fmt.Printf("Error runnning tui program: %v\n", err)
+				return err
+			}
+			stack = viper.GetString("stack")
+			service = viper.GetString("service")
+			image = viper.GetString("image")
+		} else {
+			stack = args[0]
+			service = args[1]
+			image = args[2]
+		}
+		env, err := utils.ReadDotEnv()
+		if err != nil {
+			fmt.Printf("Error reading .env file: %v\n", err)
+			return err
+		}
+		return envme.AddService(cmd.Context(), stack, service, image, network, env)
+	},
+}
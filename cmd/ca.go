@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"envme/pkg/envme"
+	"fmt"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+func init() {
+	rootCmd.AddCommand(caCmd)
+	caCmd.AddCommand(caInstallPathCmd, caExportCmd)
+}
+
+// caCmd handles the `envme ca` command
+var caCmd = &cobra.Command{
+	Use:   "ca",
+	Short: "Manage the local CA signing the certificates of `envme expose --local`",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+// caInstallPathCmd handles the `envme ca install-path` command
+var caInstallPathCmd = &cobra.Command{
+	Use:   "install-path",
+	Short: "Print the path of the root certificate to add to the trusted ones",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := envme.CAPath()
+		if err != nil {
+			return err
+		}
+		fmt.Println(path)
+		return nil
+	},
+}
+
+// caExportCmd handles the `envme ca export` command
+var caExportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Write the PEM encoded root certificate to the file or stdout",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return envme.ExportCA(os.Stdout)
+		}
+
+		file, err := os.Create(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		if err := envme.ExportCA(file); err != nil {
+			return err
+		}
+		return file.Close()
+	},
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"envme/lib/catalog"
+	"envme/pkg/envme"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+func init() {
+	rootCmd.AddCommand(catalogCmd)
+	catalogCmd.AddCommand(catalogListCmd)
+}
+
+// catalogCmd handles the `envme catalog` command
+var catalogCmd = &cobra.Command{
+	Use:   "catalog",
+	Short: "Browse the common services available to `envme create service --from`",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+// catalogListCmd handles the `envme catalog list` command
+var catalogListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the catalog services",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		list, err := catalog.List()
+		if err != nil {
+			return err
+		}
+		return envme.WriteCatalog(os.Stdout, list)
+	},
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"envme/pkg/envme"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(envCmd)
+	envCmd.AddCommand(envListCmd, envGetCmd, envSetCmd, envUnsetCmd)
+
+	// Add flags to the `envme env` commands
+	envCmd.PersistentFlags().StringP("service", "s", "", "Service of the stack to use (default: the service named after the stack)")
+}
+
+// envCmd handles the `envme env` command
+var envCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Manage the environment variables of a service",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+// envListCmd handles the `envme env list` command
+var envListCmd = &cobra.Command{
+	Use:     "list <service-name>",
+	Aliases: []string{"ls"},
+	Short:   "Print the environment variables of a service",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("\n  Please specify <service-name>\n")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, _ := cmd.Flags().GetString("service")
+		env, err := envme.ReadEnv(args[0], service)
+		if err != nil {
+			return err
+		}
+		for _, v := range env {
+			fmt.Printf("%s=%s\n", v.Key, v.Value)
+		}
+		return nil
+	},
+}
+
+// envGetCmd handles the `envme env get` command
+var envGetCmd = &cobra.Command{
+	Use:   "get <service-name> <key>",
+	Short: "Print the value of an environment variable of a service",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 2 {
+			return fmt.Errorf("\n  Please specify <service-name> and <key>\n")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, _ := cmd.Flags().GetString("service")
+		env, err := envme.ReadEnv(args[0], service)
+		if err != nil {
+			return err
+		}
+		value, ok := env.Get(args[1])
+		if !ok {
+			return fmt.Errorf("%s is not set on %s", args[1], args[0])
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+// envSetCmd handles the `envme env set` command
+var envSetCmd = &cobra.Command{
+	Use:   "set <service-name> <key>=<value>...",
+	Short: "Set environment variables of a service and recreate it",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return fmt.Errorf("\n  Please specify <service-name> and at least one <key>=<value>\n")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, _ := cmd.Flags().GetString("service")
+		return envme.SetEnv(cmd.Context(), args[0], service, args[1:])
+	},
+}
+
+// envUnsetCmd handles the `envme env unset` command
+var envUnsetCmd = &cobra.Command{
+	Use:   "unset <service-name> <key>...",
+	Short: "Remove environment variables of a service and recreate it",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 2 {
+			return fmt.Errorf("\n  Please specify <service-name> and at least one <key>\n")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		service, _ := cmd.Flags().GetString("service")
+		return envme.UnsetEnv(cmd.Context(), args[0], service, args[1:])
+	},
+}
@@ -18,32 +18,77 @@ var rootCmd = &cobra.Command{
 
 func Execute(version string) error {
 	rootCmd.Version = version
+	envme.Version = version
 	return rootCmd.Execute()
 }
 
 func init() {
+	cobra.OnInitialize(initConfig)
+
 	rootCmd.AddCommand(createCmd, exposeCmd, listCmd)
 	createCmd.AddCommand(createServiceCmd, createDevCmd)
 	listCmd.AddCommand(listServicesCmd)
 
 	// Add flags to all commands
 	rootCmd.PersistentFlags().BoolP("interactive", "i", false, "Use interactive mode")
-	_ = viper.BindPFlag("interactive", rootCmd.Flags().Lookup("interactive"))
+	_ = viper.BindPFlag("interactive", rootCmd.PersistentFlags().Lookup("interactive"))
 
 	// Add flags to the `envme create` command
 	createCmd.PersistentFlags().StringArrayP("env", "e", []string{}, "Add environment variables for service")
-	_ = viper.BindPFlag("env", createCmd.Flags().Lookup("env"))
-	createCmd.PersistentFlags().String("env-file", "", "Read in a file of environment variables")
-	_ = viper.BindPFlag("env-file", createCmd.Flags().Lookup("env-file"))
-	createCmd.PersistentFlags().StringArrayP("expose", "p", []string{}, "Expose a service to the internet")
-	_ = viper.BindPFlag("expose", createCmd.Flags().Lookup("expose"))
+	_ = viper.BindPFlag("env", createCmd.PersistentFlags().Lookup("env"))
+	createCmd.PersistentFlags().StringArray("env-file", []string{}, "Read in a file of environment variables, --env takes precedence")
+	_ = viper.BindPFlag("env-file", createCmd.PersistentFlags().Lookup("env-file"))
+	createCmd.PersistentFlags().StringArrayP("expose", "p", []string{}, "Expose a service to the internet (format: <port>:<hostname>)")
+	_ = viper.BindPFlag("expose", createCmd.PersistentFlags().Lookup("expose"))
+	createCmd.PersistentFlags().StringArrayP("volume", "v", []string{}, "Mount a volume (format: [<source>:]<target>[:ro|rw])")
+	_ = viper.BindPFlag("volume", createCmd.PersistentFlags().Lookup("volume"))
+	createCmd.PersistentFlags().StringArray("publish", []string{}, "Publish a port on the host, a free host port is assigned when omitted (format: [<host-port>:]<container-port>)")
+	_ = viper.BindPFlag("publish", createCmd.PersistentFlags().Lookup("publish"))
+	createCmd.PersistentFlags().BoolP("force", "f", false, "Overwrite an existing service")
+	_ = viper.BindPFlag("force", createCmd.PersistentFlags().Lookup("force"))
+
+	// Add flags to the `envme create service` command
+	createServiceCmd.Flags().String("from", "", "Start from a catalog service (postgres, mysql, redis, mongodb, rabbitmq, minio or mailpit)")
+	_ = viper.BindPFlag("from", createServiceCmd.Flags().Lookup("from"))
+
+	// Add flags to the `envme create development` command
+	createDevCmd.Flags().StringP("template", "t", "", "Generate the Dockerfile from a template (nextjs, nestjs or laravel)")
+	createDevCmd.Flags().StringArray("param", []string{}, "Set a template parameter (format: <key>=<value>)")
+	_ = viper.BindPFlag("param", createDevCmd.Flags().Lookup("param"))
+	createDevCmd.Flags().String("mount-path", "/app", "Path of the source code in the container, should match the WORKDIR of the Dockerfile")
+	_ = viper.BindPFlag("mount-path", createDevCmd.Flags().Lookup("mount-path"))
+	createDevCmd.Flags().Bool("no-detect", false, "Do not detect the framework when there is no Dockerfile and no --template")
+	_ = viper.BindPFlag("no-detect", createDevCmd.Flags().Lookup("no-detect"))
+
+	// Add flags to the `envme expose` command
+	exposeCmd.Flags().BoolP("local", "l", false, "Expose through the envme proxy, on <service-name>.localhost by default")
 
 	// Add flags to the `envme list` command
-	// listCmd.PersistentFlags().Bool("no-interactive", false, "List services without interactive mode")
-	// _ = viper.BindPFlag("no-interactive", listCmd.Flags().Lookup("no-interactive"))
+	listCmd.PersistentFlags().StringP("output", "o", "table", "Print services without interactive mode (table, json or yaml)")
 
 	// Default network name
 	viper.SetDefault("network", "envme")
+	// Default cloudflared tunnel name
+	viper.SetDefault("tunnel.name", "envme")
+	// Default local proxy domain and host ports
+	viper.SetDefault("proxy.domain", "localhost")
+	viper.SetDefault("proxy.http-port", 80)
+	viper.SetDefault("proxy.https-port", 443)
+}
+
+// initConfig reads the envme config file (~/.envme/config.yaml) into viper
+func initConfig() {
+	file, err := utils.GetConfigFile()
+	if err != nil {
+		fmt.Printf("Error getting config file: %v\n", err)
+		return
+	}
+
+	viper.SetConfigFile(file)
+	err = viper.ReadInConfig()
+	if err != nil {
+		fmt.Printf("Error reading config file: %v\n", err)
+	}
 }
 
 // createCmd handles the `envme create` command
@@ -70,14 +115,29 @@ var listCmd = &cobra.Command{
 var exposeCmd = &cobra.Command{
 	Use:     "expose <service-name> <port> <hostname>",
 	Aliases: []string{"publish", "p"},
-	Short:   "Expose a service to the internet",
+	Short:   "Expose a service to the internet, or locally with --local",
 	Args: func(cmd *cobra.Command, args []string) error {
+		if local, _ := cmd.Flags().GetBool("local"); local {
+			if len(args) < 2 {
+				return fmt.Errorf("\n  Please specify <service-name> and <port>\n")
+			}
+			return nil
+		}
 		if len(args) < 3 && !cmd.Flags().Changed("interactive") {
 			return fmt.Errorf("\n  Please specify <service-name>, <port> and <hostname> or using interactive mode\n")
 		}
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		network := viper.GetString("network")
+		if local, _ := cmd.Flags().GetBool("local"); local {
+			var hostname string
+			if len(args) > 2 {
+				hostname = args[2]
+			}
+			return envme.ExposeLocal(cmd.Context(), args[0], args[1], hostname, network)
+		}
+
 		var name, port, hostname string
 		if len(args) < 3 && cmd.Flags().Changed("interactive") {
 			_, err := tea.NewProgram(tui.NewExposeForm()).Run()
@@ -92,8 +152,7 @@ var exposeCmd = &cobra.Command{
 			port = args[1]
 			hostname = args[2]
 		}
-		fmt.Printf("Exposing service %s on port %s with hostname %s\n", name, port, hostname)
-		return nil
+		return envme.Expose(cmd.Context(), name, port, hostname, network)
 	},
 }
 
@@ -103,15 +162,19 @@ var createServiceCmd = &cobra.Command{
 	Aliases: []string{"srv", "s"},
 	Short:   "Create a new service",
 	Args: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flags().Changed("from") && len(args) == 1 {
+			return nil
+		}
 		if len(args) < 2 && !cmd.Flags().Changed("interactive") {
-			return fmt.Errorf("\n  Please specify <service-name> and <image-name> or using interactive mode\n")
+			return fmt.Errorf("\n  Please specify <service-name> and <image-name>, --from or using interactive mode\n")
 		}
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var name, image string
 		network := viper.GetString("network")
-		if len(args) < 2 && cmd.Flags().Changed("interactive") {
+		fromCatalog := cmd.Flags().Changed("from") && len(args) == 1
+		if len(args) < 2 && !fromCatalog && cmd.Flags().Changed("interactive") {
 			_, err := tea.NewProgram(tui.NewServiceForm()).Run()
 			if err != nil {
 				fmt.Printf("Error runnning tui program: %v\n", err)
@@ -121,14 +184,16 @@ var createServiceCmd = &cobra.Command{
 			image = viper.GetString("image")
 		} else {
 			name = args[0]
-			image = args[1]
+			if len(args) > 1 {
+				image = args[1]
+			}
 		}
-		err := utils.ReadDotEnv()
+		env, err := utils.ReadDotEnv()
 		if err != nil {
 			fmt.Printf("Error reading .env file: %v\n", err)
 			return err
 		}
-		return envme.CreateService(cmd.Context(), name, image, network)
+		return envme.CreateService(cmd.Context(), name, image, network, env)
 	},
 }
 
@@ -164,27 +229,40 @@ var createDevCmd = &cobra.Command{
 		} else {
 			name = args[0]
 			dir = args[1]
+			template, _ = cmd.Flags().GetString("template")
 		}
-		err := utils.ReadDotEnv()
+		env, err := utils.ReadDotEnv()
 		if err != nil {
 			fmt.Printf("Error reading .env file: %v\n", err)
 			return err
 		}
-		return envme.CreateDev(cmd.Context(), name, dir, template, viper.GetString("network"))
+		return envme.CreateDev(cmd.Context(), name, dir, template, viper.GetString("network"), env)
 	},
 }
 
 // listServicesCmd handles the `envme list services` command
 var listServicesCmd = &cobra.Command{
-	Use:     "service",
-	Aliases: []string{"srv", "s"},
+	Use:     "services",
+	Aliases: []string{"service", "srv", "s"},
 	Short:   "List services",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, err := tea.NewProgram(tui.NewListService()).Run()
+		services, err := envme.ListServices(cmd.Context())
 		if err != nil {
+			fmt.Printf("Error listing services: %v\n", err)
 			return err
 		}
-		fmt.Println("Listing services")
-		return nil
+
+		if cmd.Flags().Changed("output") {
+			output, _ := cmd.Flags().GetString("output")
+			return envme.WriteServices(os.Stdout, services, output)
+		}
+
+		_, err = tea.NewProgram(tui.NewListService(services)).Run()
+		for _, s := range services {
+			if s.Error != "" {
+				fmt.Printf("Error on %s: %s\n", s.Name, s.Error)
+			}
+		}
+		return err
 	},
 }
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"envme/pkg/envme"
+	"fmt"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"os"
+)
+
+func init() {
+	rootCmd.AddCommand(execCmd, shellCmd)
+
+	// Add flags to the `envme exec` and `envme shell` commands
+	addRunFlags(execCmd)
+	addRunFlags(shellCmd)
+	// Flags after <service-name> belong to the command run in the container
+	execCmd.Flags().SetInterspersed(false)
+}
+
+// addRunFlags adds the flags shared by `envme exec` and `envme shell`
+func addRunFlags(c *cobra.Command) {
+	c.Flags().StringP("service", "s", "", "Service of the stack to use (default: the service named after the stack)")
+	c.Flags().StringP("user", "u", "", "Run the command as this user")
+	c.Flags().StringP("workdir", "w", "", "Path to workdir directory for this command")
+	c.Flags().StringArrayP("env", "e", []string{}, "Set environment variables")
+	c.Flags().BoolP("no-TTY", "T", !term.IsTerminal(int(os.Stdin.Fd())), "Disable pseudo-TTY allocation")
+}
+
+// runOptions reads the flags shared by `envme exec` and `envme shell`
+func runOptions(cmd *cobra.Command) api.RunOptions {
+	service, _ := cmd.Flags().GetString("service")
+	user, _ := cmd.Flags().GetString("user")
+	workdir, _ := cmd.Flags().GetString("workdir")
+	env, _ := cmd.Flags().GetStringArray("env")
+	noTty, _ := cmd.Flags().GetBool("no-TTY")
+
+	return api.RunOptions{
+		Service:     service,
+		User:        user,
+		WorkingDir:  workdir,
+		Environment: env,
+		Tty:         !noTty,
+		Interactive: true,
+	}
+}
+
+// execCommand returns the command following <service-name>. Parsing stops at
+// the first positional argument, so pflag leaves the -- separator in the args.
+func execCommand(args []string) []string {
+	command := args[1:]
+	if len(command) > 0 && command[0] == "--" {
+		command = command[1:]
+	}
+	return command
+}
+
+// exitWith exits with the status code of the command run in the container
+func exitWith(code int, err error) error {
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		os.Exit(code)
+	}
+	return nil
+}
+
+// execCmd handles the `envme exec` command
+var execCmd = &cobra.Command{
+	Use:   "exec <service-name> -- <command> [args...]",
+	Short: "Execute a command in a running service",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 || len(execCommand(args)) == 0 {
+			return fmt.Errorf("\n  Please specify <service-name> and <command>\n")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		options := runOptions(cmd)
+		options.Command = execCommand(args)
+		return exitWith(envme.Exec(cmd.Context(), args[0], options))
+	},
+}
+
+// shellCmd handles the `envme shell` command
+var shellCmd = &cobra.Command{
+	Use:     "shell <service-name>",
+	Aliases: []string{"sh"},
+	Short:   "Open a shell in a running service",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("\n  Please specify <service-name>\n")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return exitWith(envme.Shell(cmd.Context(), args[0], runOptions(cmd)))
+	},
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"reflect"
+	"testing"
+)
+
+// newExecCmd returns a command with the flags of `envme exec`, fresh for each
+// case so that no flag value leaks into the next one.
+func newExecCmd() *cobra.Command {
+	c := &cobra.Command{Use: execCmd.Use, Args: execCmd.Args}
+	addRunFlags(c)
+	c.Flags().SetInterspersed(false)
+	return c
+}
+
+func TestExecCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+		user string
+		env  []string
+	}{
+		{args: []string{"web", "--", "ls", "-la"}, want: []string{"ls", "-la"}},
+		{args: []string{"-u", "root", "-e", "A=1", "web", "--", "ls", "-la"}, want: []string{"ls", "-la"}, user: "root", env: []string{"A=1"}},
+		{args: []string{"web", "ls", "-la"}, want: []string{"ls", "-la"}},
+		{args: []string{"web", "-u", "root", "id"}, want: []string{"-u", "root", "id"}},
+		{args: []string{"web", "--", "sh", "-c", "echo --"}, want: []string{"sh", "-c", "echo --"}},
+		{args: []string{"web", "--"}, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		cmd := newExecCmd()
+		if err := cmd.Flags().Parse(tt.args); err != nil {
+			t.Fatalf("parse %v: %v", tt.args, err)
+		}
+		got := execCommand(cmd.Flags().Args())
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("execCommand(%v) = %q, want %q", tt.args, got, tt.want)
+		}
+
+		options := runOptions(cmd)
+		if options.User != tt.user || !reflect.DeepEqual(options.Environment, append([]string{}, tt.env...)) {
+			t.Errorf("runOptions(%v) = user %q, env %q, want user %q, env %q", tt.args, options.User, options.Environment, tt.user, tt.env)
+		}
+	}
+}
+
+func TestExecArgs(t *testing.T) {
+	for _, args := range [][]string{{}, {"web"}, {"web", "--"}} {
+		cmd := newExecCmd()
+		if err := cmd.Flags().Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.Args(cmd, cmd.Flags().Args()); err == nil {
+			t.Errorf("args %q accepted without a command", args)
+		}
+	}
+}
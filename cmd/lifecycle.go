@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"envme/pkg/envme"
+	"fmt"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(downCmd, startCmd, stopCmd, restartCmd)
+
+	for _, c := range []*cobra.Command{downCmd, startCmd, stopCmd, restartCmd} {
+		c.Flags().BoolP("all", "a", false, "Apply to every service in ~/.envme")
+	}
+
+	// Add flags to the `envme down` command
+	downCmd.Flags().BoolP("volumes", "v", false, "Remove named volumes declared in the service")
+	downCmd.Flags().String("rmi", "", `Remove images used by the service ("local" or "all")`)
+}
+
+// lifecycleArgs requires at least one service name unless --all is set
+func lifecycleArgs(cmd *cobra.Command, args []string) error {
+	all, _ := cmd.Flags().GetBool("all")
+	if len(args) == 0 && !all {
+		return fmt.Errorf("\n  Please specify <service-name> or use --all\n")
+	}
+	return nil
+}
+
+// downCmd handles the `envme down` command
+var downCmd = &cobra.Command{
+	Use:   "down <service-name>...",
+	Short: "Stop and remove the containers of services",
+	Args:  lifecycleArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		volumes, _ := cmd.Flags().GetBool("volumes")
+		rmi, _ := cmd.Flags().GetString("rmi")
+		if rmi != "" && rmi != "local" && rmi != "all" {
+			return fmt.Errorf("invalid --rmi %q, expected local or all", rmi)
+		}
+
+		names, err := envme.ResolveStacks(args, all)
+		if err != nil {
+			return err
+		}
+		return envme.EachStack(names, func(name string) error {
+			return envme.Down(cmd.Context(), name, api.DownOptions{Volumes: volumes, Images: rmi})
+		})
+	},
+}
+
+// startCmd handles the `envme start` command
+var startCmd = &cobra.Command{
+	Use:   "start <service-name>...",
+	Short: "Start stopped services",
+	Args:  lifecycleArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		names, err := envme.ResolveStacks(args, all)
+		if err != nil {
+			return err
+		}
+		return envme.EachStack(names, func(name string) error {
+			return envme.Start(cmd.Context(), name)
+		})
+	},
+}
+
+// stopCmd handles the `envme stop` command
+var stopCmd = &cobra.Command{
+	Use:   "stop <service-name>...",
+	Short: "Stop running services",
+	Args:  lifecycleArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		names, err := envme.ResolveStacks(args, all)
+		if err != nil {
+			return err
+		}
+		return envme.EachStack(names, func(name string) error {
+			return envme.Stop(cmd.Context(), name)
+		})
+	},
+}
+
+// restartCmd handles the `envme restart` command
+var restartCmd = &cobra.Command{
+	Use:   "restart <service-name>...",
+	Short: "Restart services",
+	Args:  lifecycleArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		names, err := envme.ResolveStacks(args, all)
+		if err != nil {
+			return err
+		}
+		return envme.EachStack(names, func(name string) error {
+			return envme.Restart(cmd.Context(), name)
+		})
+	},
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"envme/pkg/envme"
+	"fmt"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+
+	// Add flags to the `envme logs` command
+	logsCmd.Flags().BoolP("all", "a", false, "Show logs of every service in ~/.envme")
+	logsCmd.Flags().BoolP("follow", "f", false, "Follow log output")
+	logsCmd.Flags().StringP("tail", "n", "all", "Number of lines to show from the end of the logs")
+	logsCmd.Flags().String("since", "", "Show logs since timestamp (e.g. 2013-01-02T13:23:37Z) or relative (e.g. 42m)")
+	logsCmd.Flags().BoolP("timestamps", "t", false, "Show timestamps")
+}
+
+// logsCmd handles the `envme logs` command
+var logsCmd = &cobra.Command{
+	Use:   "logs <service-name>...",
+	Short: "Show the logs of services",
+	Args: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		if len(args) == 0 && !all {
+			return fmt.Errorf("\n  Please specify <service-name> or use --all\n")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		follow, _ := cmd.Flags().GetBool("follow")
+		tail, _ := cmd.Flags().GetString("tail")
+		since, _ := cmd.Flags().GetString("since")
+		timestamps, _ := cmd.Flags().GetBool("timestamps")
+
+		names, err := envme.ResolveStacks(args, all)
+		if err != nil {
+			return err
+		}
+		return envme.Logs(cmd.Context(), names, api.LogOptions{
+			Follow:     follow,
+			Tail:       tail,
+			Since:      since,
+			Timestamps: timestamps,
+		})
+	},
+}
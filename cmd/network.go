@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"envme/pkg/envme"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"os"
+)
+
+func init() {
+	rootCmd.AddCommand(networkCmd)
+	networkCmd.AddCommand(networkInspectCmd, networkPruneCmd)
+
+	// Default network settings, overridable in ~/.envme/config.yaml
+	viper.SetDefault("network-config.driver", "bridge")
+}
+
+// networkCmd handles the `envme network` command
+var networkCmd = &cobra.Command{
+	Use:     "network",
+	Aliases: []string{"net"},
+	Short:   "Manage the envme network",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+// networkInspectCmd handles the `envme network inspect` command
+var networkInspectCmd = &cobra.Command{
+	Use:   "inspect",
+	Short: "Show the envme network and its containers",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return envme.InspectNetwork(cmd.Context(), os.Stdout, viper.GetString("network"))
+	},
+}
+
+// networkPruneCmd handles the `envme network prune` command
+var networkPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove unused networks created by envme",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return envme.PruneNetworks(cmd.Context())
+	},
+}
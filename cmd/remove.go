@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"envme/pkg/envme"
+	"fmt"
+	"github.com/charmbracelet/huh"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+func init() {
+	rootCmd.AddCommand(removeCmd)
+
+	// Add flags to the `envme rm` command
+	removeCmd.Flags().BoolP("force", "f", false, "Do not prompt for confirmation and ignore teardown errors")
+	removeCmd.Flags().BoolP("volumes", "v", false, "Remove named volumes declared in the service")
+	removeCmd.Flags().String("rmi", "", `Remove images used by the service ("local" or "all")`)
+}
+
+// removeCmd handles the `envme rm` command
+var removeCmd = &cobra.Command{
+	Use:     "rm <service-name>...",
+	Aliases: []string{"remove", "delete"},
+	Short:   "Remove services and their ~/.envme directory",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("\n  Please specify <service-name>\n")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		volumes, _ := cmd.Flags().GetBool("volumes")
+		rmi, _ := cmd.Flags().GetString("rmi")
+		if rmi != "" && rmi != "local" && rmi != "all" {
+			return fmt.Errorf("invalid --rmi %q, expected local or all", rmi)
+		}
+
+		if !force {
+			var confirmed bool
+			err := huh.NewConfirm().
+				Title(fmt.Sprintf("Remove %s?", strings.Join(args, ", "))).
+				Description("Containers and the service directory will be deleted").
+				Affirmative("Yep").
+				Negative("Wait, no").
+				Value(&confirmed).
+				Run()
+			if err != nil {
+				return err
+			}
+			if !confirmed {
+				return nil
+			}
+		}
+
+		return envme.EachStack(args, func(name string) error {
+			return envme.Remove(cmd.Context(), name, api.DownOptions{Volumes: volumes, Images: rmi}, force)
+		})
+	},
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"envme/pkg/envme"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"io"
+	"os"
+	"strings"
+)
+
+func init() {
+	rootCmd.AddCommand(secretCmd)
+	secretCmd.AddCommand(secretListCmd, secretGetCmd, secretSetCmd, secretRemoveCmd)
+}
+
+// secretCmd handles the `envme secret` command
+var secretCmd = &cobra.Command{
+	Use:     "secret",
+	Aliases: []string{"secrets"},
+	Short:   "Manage encrypted secrets, referenced as --env KEY=secret://<name>",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+// secretListCmd handles the `envme secret list` command
+var secretListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "Print the names of the secrets",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		names, err := envme.ListSecrets()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return nil
+	},
+}
+
+// secretGetCmd handles the `envme secret get` command
+var secretGetCmd = &cobra.Command{
+	Use:   "get <name>",
+	Short: "Print the value of a secret",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("\n  Please specify <name>\n")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		value, err := envme.GetSecret(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+// secretSetCmd handles the `envme secret set` command
+var secretSetCmd = &cobra.Command{
+	Use:   "set <name> [value]",
+	Short: "Encrypt and store a secret, read from the prompt or stdin when no value is given",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) < 1 || len(args) > 2 {
+			return fmt.Errorf("\n  Please specify <name> and optionally [value]\n")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var value string
+		if len(args) == 2 {
+			value = args[1]
+		} else {
+			var err error
+			value, err = readSecret(args[0])
+			if err != nil {
+				return err
+			}
+		}
+		return envme.SetSecret(args[0], value)
+	},
+}
+
+// secretRemoveCmd handles the `envme secret rm` command
+var secretRemoveCmd = &cobra.Command{
+	Use:     "rm <name>...",
+	Aliases: []string{"remove", "delete"},
+	Short:   "Remove secrets",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("\n  Please specify <name>\n")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var errs []error
+		for _, name := range args {
+			if err := envme.RemoveSecret(name); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	},
+}
+
+// readSecret prompts for the value without echo on a terminal, or reads the whole stdin
+func readSecret(name string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Printf("Value of %s: ", name)
+		value, err := term.ReadPassword(fd)
+		fmt.Println()
+		return string(value), err
+	}
+
+	value, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(value), "\n"), nil
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"envme/lib/templates"
+	"envme/pkg/envme"
+	"fmt"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+)
+
+func init() {
+	rootCmd.AddCommand(templateCmd)
+	templateCmd.AddCommand(templateListCmd, templateShowCmd, templateNewCmd)
+
+	// Add flags to the `envme template show` command
+	templateShowCmd.Flags().Bool("render", false, "Render the Dockerfile with the template parameters")
+	templateShowCmd.Flags().StringArray("param", []string{}, "Set a template parameter (format: <key>=<value>)")
+
+	// Add flags to the `envme template new` command
+	templateNewCmd.Flags().String("from", "", "Copy an existing template")
+	templateNewCmd.Flags().Bool("local", false, "Create the template in .envme/templates of the current directory")
+}
+
+// templateCmd handles the `envme template` command
+var templateCmd = &cobra.Command{
+	Use:     "template",
+	Aliases: []string{"templates", "tpl"},
+	Short:   "Manage Dockerfile templates",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+// templateListCmd handles the `envme template list` command
+var templateListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List builtin and user templates",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		list, err := envme.LoadTemplates(cwd)
+		if err != nil {
+			return err
+		}
+		return envme.WriteTemplates(os.Stdout, list)
+	},
+}
+
+// templateShowCmd handles the `envme template show` command
+var templateShowCmd = &cobra.Command{
+	Use:   "show <template-name>",
+	Short: "Show the manifest and Dockerfile of a template",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("\n  Please specify <template-name>\n")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		t, err := envme.FindTemplate(args[0], cwd)
+		if err != nil {
+			return err
+		}
+
+		var dockerfile []byte
+		if render, _ := cmd.Flags().GetBool("render"); render {
+			specs, _ := cmd.Flags().GetStringArray("param")
+			params, err := templates.ParseParams(specs)
+			if err != nil {
+				return err
+			}
+			dockerfile, err = t.Render(params)
+			if err != nil {
+				return err
+			}
+		} else {
+			dockerfile, err = t.Dockerfile()
+			if err != nil {
+				return err
+			}
+		}
+
+		manifest, err := yaml.Marshal(t)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("# %s (%s)\n%s\n%s", t.Name, t.Source, manifest, dockerfile)
+		return nil
+	},
+}
+
+// templateNewCmd handles the `envme template new` command
+var templateNewCmd = &cobra.Command{
+	Use:   "new <template-name>",
+	Short: "Create a user template",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return fmt.Errorf("\n  Please specify <template-name>\n")
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		dirs, err := envme.TemplateDirs(cwd)
+		if err != nil {
+			return err
+		}
+		dir := dirs[0]
+		if local, _ := cmd.Flags().GetBool("local"); local {
+			dir = filepath.Join(cwd, ".envme", "templates")
+		}
+
+		var from *templates.Template
+		if name, _ := cmd.Flags().GetString("from"); name != "" {
+			from, err = envme.FindTemplate(name, cwd)
+			if err != nil {
+				return err
+			}
+		}
+
+		t, err := templates.Create(dir, args[0], from)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created template %s in %s\n", t.Name, t.Source)
+		return nil
+	},
+}
@@ -0,0 +1,93 @@
+package catalog
+
+import (
+	"crypto/rand"
+	"embed"
+	"envme/lib/types"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"math/big"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed services/*.yaml
+var services embed.FS
+
+const passwordChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Entry is a curated definition of a common backing service, used by
+// `envme create service --from <name>`.
+type Entry struct {
+	Name        string `yaml:"-"`
+	Title       string `yaml:"title"`
+	Description string `yaml:"description,omitempty"`
+	Image       string `yaml:"image"`
+	Port        int    `yaml:"port,omitempty"`
+	// Env holds the default KEY=VALUE assignments, --env takes precedence
+	Env []string `yaml:"env,omitempty"`
+	// Generate lists the variables set to a random password unless given with --env
+	Generate []string `yaml:"generate,omitempty"`
+	// Volumes are mounted before the --volume ones, named volumes persist the data
+	Volumes []string `yaml:"volumes,omitempty"`
+	// Compose is merged into the service generated for the entry
+	Compose *types.Service `yaml:"compose,omitempty"`
+}
+
+// List returns the catalog entries sorted by name.
+func List() ([]*Entry, error) {
+	files, err := services.ReadDir("services")
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*Entry, 0, len(files))
+	for _, file := range files {
+		content, err := services.ReadFile(path.Join("services", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		e := &Entry{}
+		if err := yaml.Unmarshal(content, e); err != nil {
+			return nil, fmt.Errorf("invalid catalog entry %s: %w", file.Name(), err)
+		}
+		e.Name = strings.TrimSuffix(file.Name(), path.Ext(file.Name()))
+		list = append(list, e)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// Find returns the entry matching the name or title, ignoring case.
+func Find(name string) (*Entry, error) {
+	list, err := List()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range list {
+		if strings.EqualFold(e.Name, name) || strings.EqualFold(e.Title, name) {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown catalog service %q, see `envme catalog list`", name)
+}
+
+// GeneratePassword returns a random alphanumeric password, safe to use in URLs and shells.
+func GeneratePassword(length int) (string, error) {
+	max := big.NewInt(int64(len(passwordChars)))
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = passwordChars[n.Int64()]
+	}
+	return string(b), nil
+}
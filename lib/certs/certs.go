@@ -0,0 +1,207 @@
+package certs
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// CertFile and KeyFile hold the root certificate and its key, in the CA directory
+	CertFile = "ca.pem"
+	KeyFile  = "ca-key.pem"
+
+	caValidity = 10 * 365 * 24 * time.Hour
+	// Browsers reject server certificates valid for more than 398 days
+	leafValidity = 397 * 24 * time.Hour
+	// Certificates are issued again when they expire within renewBefore
+	renewBefore = 30 * 24 * time.Hour
+)
+
+// CA is the local certificate authority signing the certificates of the
+// hostnames exposed through the envme proxy.
+type CA struct {
+	Cert *x509.Certificate
+	Key  crypto.Signer
+	// PEM is the encoded root certificate, to be trusted by the system and browsers
+	PEM []byte
+}
+
+// LoadOrCreateCA loads the CA stored in dir, generating it on first use.
+func LoadOrCreateCA(dir string) (*CA, error) {
+	ca, err := LoadCA(dir)
+	if !errors.Is(err, os.ErrNotExist) {
+		return ca, err
+	}
+
+	ca, keyPEM, err := NewCA(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, KeyFile), keyPEM, 0600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, CertFile), ca.PEM, 0644); err != nil {
+		return nil, err
+	}
+	return ca, nil
+}
+
+// LoadCA loads the CA stored in dir.
+func LoadCA(dir string) (*CA, error) {
+	certPEM, err := os.ReadFile(filepath.Join(dir, CertFile))
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := os.ReadFile(filepath.Join(dir, KeyFile))
+	if err != nil {
+		return nil, err
+	}
+
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", CertFile, err)
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("invalid %s: no PEM block", KeyFile)
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", KeyFile, err)
+	}
+
+	return &CA{Cert: cert, Key: key, PEM: certPEM}, nil
+}
+
+// NewCA generates a root certificate valid from now, returning the CA and its encoded key.
+func NewCA(now time.Time) (*CA, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{"envme"},
+			CommonName:   "envme local CA",
+		},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &CA{Cert: cert, Key: key, PEM: encodeCert(der)}, keyPEM, nil
+}
+
+// Issue returns a server certificate for the hostname valid from now, signed by
+// the CA, and its key, both PEM encoded.
+func (ca *CA) Issue(hostname string, now time.Time) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{"envme"},
+			CommonName:   hostname,
+		},
+		DNSNames:    []string{hostname},
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    now.Add(leafValidity),
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, key.Public(), ca.Key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encodeCert(der), keyPEM, nil
+}
+
+// Valid reports whether the PEM encoded certificate was signed by the CA for
+// the hostname and does not need to be renewed yet.
+func (ca *CA) Valid(certPEM []byte, hostname string, now time.Time) bool {
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		return false
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+	_, err = cert.Verify(x509.VerifyOptions{
+		DNSName:     hostname,
+		Roots:       roots,
+		CurrentTime: now.Add(renewBefore),
+	})
+	return err == nil
+}
+
+// ParseCertificate parses the first certificate of PEM encoded content.
+func ParseCertificate(content []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(content)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no certificate PEM block")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func newSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func encodeCert(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
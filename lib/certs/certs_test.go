@@ -0,0 +1,179 @@
+package certs
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newCA(t *testing.T, now time.Time) *CA {
+	t.Helper()
+	ca, _, err := NewCA(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca
+}
+
+func TestNewCA(t *testing.T) {
+	now := time.Now()
+	ca, keyPEM, err := NewCA(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ca.Cert.IsCA || !ca.Cert.BasicConstraintsValid {
+		t.Error("the root certificate is not a CA")
+	}
+	if ca.Cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		t.Error("the root certificate cannot sign certificates")
+	}
+	if !ca.Cert.NotAfter.After(now.Add(5 * 365 * 24 * time.Hour)) {
+		t.Errorf("the root certificate expires on %s", ca.Cert.NotAfter)
+	}
+	if err := ca.Cert.CheckSignatureFrom(ca.Cert); err != nil {
+		t.Errorf("the root certificate is not self-signed: %v", err)
+	}
+
+	cert, err := ParseCertificate(ca.PEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cert.Equal(ca.Cert) {
+		t.Error("PEM does not hold the root certificate")
+	}
+	if _, err := tls.X509KeyPair(ca.PEM, keyPEM); err != nil {
+		t.Errorf("the key does not match the root certificate: %v", err)
+	}
+}
+
+func TestIssue(t *testing.T) {
+	now := time.Now()
+	ca := newCA(t, now)
+
+	certPEM, keyPEM, err := ca.Issue("api.localhost", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		t.Fatalf("the key does not match the certificate: %v", err)
+	}
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.IsCA {
+		t.Error("the certificate of a hostname is a CA")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+	_, err = cert.Verify(x509.VerifyOptions{
+		DNSName:     "api.localhost",
+		Roots:       roots,
+		CurrentTime: now,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	if err != nil {
+		t.Errorf("the certificate does not verify against the root: %v", err)
+	}
+	if err := cert.VerifyHostname("web.localhost"); err == nil {
+		t.Error("the certificate is valid for another hostname")
+	}
+}
+
+func TestValid(t *testing.T) {
+	now := time.Now()
+	ca := newCA(t, now)
+	certPEM, _, err := ca.Issue("api.localhost", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := newCA(t, now)
+	otherPEM, _, err := other.Issue("api.localhost", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		certPEM  []byte
+		hostname string
+		now      time.Time
+		want     bool
+	}{
+		{name: "fresh", certPEM: certPEM, hostname: "api.localhost", now: now, want: true},
+		{name: "before the renewal window", certPEM: certPEM, hostname: "api.localhost", now: cert.NotAfter.Add(-renewBefore - time.Hour), want: true},
+		{name: "within the renewal window", certPEM: certPEM, hostname: "api.localhost", now: cert.NotAfter.Add(-renewBefore + time.Hour), want: false},
+		{name: "expired", certPEM: certPEM, hostname: "api.localhost", now: cert.NotAfter.Add(time.Hour), want: false},
+		{name: "another hostname", certPEM: certPEM, hostname: "web.localhost", now: now, want: false},
+		{name: "signed by another CA", certPEM: otherPEM, hostname: "api.localhost", now: now, want: false},
+		{name: "not a certificate", certPEM: []byte("garbage"), hostname: "api.localhost", now: now, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ca.Valid(tt.certPEM, tt.hostname, tt.now); got != tt.want {
+				t.Errorf("Valid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadOrCreateCA(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ca")
+
+	created, err := LoadOrCreateCA(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string]os.FileMode{CertFile: 0644, KeyFile: 0600} {
+		info, err := os.Stat(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != want {
+			t.Errorf("%s mode = %v, want %v", file, info.Mode().Perm(), want)
+		}
+	}
+
+	loaded, err := LoadOrCreateCA(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Cert.Equal(created.Cert) || !bytes.Equal(loaded.PEM, created.PEM) {
+		t.Fatal("a new CA was created instead of loading the existing one")
+	}
+
+	// Certificates issued before reloading stay valid
+	certPEM, _, err := created.Issue("api.localhost", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.Valid(certPEM, "api.localhost", time.Now()) {
+		t.Error("a certificate of the created CA is not valid for the loaded one")
+	}
+	// And the loaded key signs like the created one
+	certPEM, _, err = loaded.Issue("web.localhost", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created.Valid(certPEM, "web.localhost", time.Now()) {
+		t.Error("a certificate of the loaded CA is not valid for the created one")
+	}
+}
+
+func TestLoadCAMissing(t *testing.T) {
+	if _, err := LoadCA(t.TempDir()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadCA() error = %v, want not exist", err)
+	}
+}
@@ -0,0 +1,37 @@
+package docker
+
+import (
+	"github.com/docker/cli/cli/command"
+	"github.com/docker/cli/cli/flags"
+	"github.com/docker/docker/client"
+)
+
+// NewDockerCli is a function that creates a Docker CLI bound to the default context.
+// It is shared by the compose service and the plain Docker API client so both
+// talk to the same daemon.
+func NewDockerCli() (command.Cli, error) {
+	dockerCli, err := command.NewDockerCli()
+	if err != nil {
+		return nil, err
+	}
+
+	dockerContext := "default"
+
+	opts := &flags.ClientOptions{Context: dockerContext, LogLevel: "error"}
+	err = dockerCli.Initialize(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return dockerCli, nil
+}
+
+// NewClient is a function that returns a Docker API client for the default context.
+func NewClient() (client.APIClient, error) {
+	dockerCli, err := NewDockerCli()
+	if err != nil {
+		return nil, err
+	}
+
+	return dockerCli.Client(), nil
+}
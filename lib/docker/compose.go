@@ -3,40 +3,32 @@ package docker
 import (
 	"context"
 	"envme/lib/utils"
+	"fmt"
 	"github.com/compose-spec/compose-go/v2/loader"
 	"github.com/compose-spec/compose-go/v2/types"
-	"github.com/docker/cli/cli/command"
-	"github.com/docker/cli/cli/flags"
 	"github.com/docker/compose/v2/pkg/api"
 	"github.com/docker/compose/v2/pkg/compose"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
 // NewCompose is a function that creates a Docker project from a given Docker Compose file.
 // It takes a context and a string representation of the Docker Compose file as input.
 // It returns a pointer to a types.Project which represents the Docker project.
-func NewCompose(ctx context.Context, stackName string) (api.Service, *types.Project, error) {
+// The routes are published through the envme proxy with labels on the service named after the stack.
+func NewCompose(ctx context.Context, stackName string, routes ...Route) (api.Service, *types.Project, error) {
 	dir, err := utils.GetServiceDir(stackName)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	file := filepath.Join(dir, "docker-compose.yaml")
-	configDetails := types.ConfigDetails{
-		WorkingDir:  dir,
-		ConfigFiles: []types.ConfigFile{{Filename: file}},
-		Environment: utils.ConvertEnvToMap(),
-	}
-
-	project, err := loader.LoadWithContext(ctx, configDetails, func(options *loader.Options) {
-		options.SetProjectName(stackName, true)
-	})
+	project, err := loadProject(ctx, stackName, types.ConfigFile{Filename: filepath.Join(dir, "docker-compose.yaml")}, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	addServiceLabels(project)
+	addServiceLabels(project, routes)
 
 	service, err := createService()
 	if err != nil {
@@ -46,33 +38,90 @@ func NewCompose(ctx context.Context, stackName string) (api.Service, *types.Proj
 	return service, project, nil
 }
 
-// createService is a function that creates a Docker service from a given Docker Compose service.
-// It takes a pointer to a types.Service as input.
-// It returns a pointer to a types.Service which represents the Docker service.
-func createService() (api.Service, error) {
-	var srv api.Service
-	dockerCli, err := command.NewDockerCli()
+// ValidateCompose loads the docker-compose.yaml content of a stack without
+// writing it, checking it against the compose schema and consistency rules.
+// Env files are not read, they may not have been written yet.
+func ValidateCompose(ctx context.Context, stackName string, content []byte) (*types.Project, error) {
+	return loadContent(ctx, stackName, content, func(options *loader.Options) {
+		options.SkipResolveEnvironment = true
+	})
+}
+
+// ParseCompose converts the docker-compose.yaml content of a stack to the project it describes as written:
+// variables are not interpolated, env files are not read and paths are not resolved, so that the project
+// marshals back to the same file. The content is not checked against the compose schema nor the consistency
+// rules, see ValidateCompose.
+func ParseCompose(ctx context.Context, stackName string, content []byte) (*types.Project, error) {
+	return loadContent(ctx, stackName, content, func(options *loader.Options) {
+		options.SkipInterpolation = true
+		options.SkipResolveEnvironment = true
+		options.ResolvePaths = false
+		options.SkipNormalization = true
+		options.SkipValidation = true
+		options.SkipConsistencyCheck = true
+	})
+}
+
+func loadContent(ctx context.Context, stackName string, content []byte, option func(*loader.Options)) (*types.Project, error) {
+	appDir, err := utils.GetAppDir()
 	if err != nil {
 		return nil, err
 	}
 
-	dockerContext := "default"
+	// The stack directory is not created, the file is only read from content
+	file := types.ConfigFile{Filename: filepath.Join(appDir, stackName, "docker-compose.yaml"), Content: content}
+	return loadProject(ctx, stackName, file, option)
+}
 
-	opts := &flags.ClientOptions{Context: dockerContext, LogLevel: "error"}
-	err = dockerCli.Initialize(opts)
+// loadProject loads the compose file of a stack, interpolating the variables from the .env of the stack.
+func loadProject(ctx context.Context, stackName string, file types.ConfigFile, option func(*loader.Options)) (*types.Project, error) {
+	env := utils.Env{}
+	// Checked first, reading would create the directory of a stack that is only validated
+	if utils.HasServiceFile(stackName, utils.EnvFile) {
+		var err error
+		env, err = utils.ReadEnvFile(stackName, utils.EnvFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	configDetails := types.ConfigDetails{
+		WorkingDir:  filepath.Dir(file.Filename),
+		ConfigFiles: []types.ConfigFile{file},
+		Environment: env.Map(),
+	}
+
+	return loader.LoadWithContext(ctx, configDetails, func(options *loader.Options) {
+		options.SetProjectName(stackName, true)
+		if option != nil {
+			option(options)
+		}
+	})
+}
+
+// createService is a function that creates a Docker service from a given Docker Compose service.
+// It takes a pointer to a types.Service as input.
+// It returns a pointer to a types.Service which represents the Docker service.
+func createService() (api.Service, error) {
+	dockerCli, err := NewDockerCli()
 	if err != nil {
 		return nil, err
 	}
 
-	srv = compose.NewComposeService(dockerCli)
+	return compose.NewComposeService(dockerCli), nil
+}
 
-	return srv, err
+// Route sends the requests for a hostname to a port of a service through the envme proxy.
+type Route struct {
+	Hostname string
+	Port     int
 }
 
 // addServiceLabels adds the labels docker compose expects to exist on services.
 // This is required for future compose operations to work, such as finding
-// containers that are part of a service.
-func addServiceLabels(project *types.Project) {
+// containers that are part of a service. The service named after the project
+// also gets the labels of the proxy routes.
+func addServiceLabels(project *types.Project, routes []Route) {
 	for i, s := range project.Services {
 		s.CustomLabels = map[string]string{
 			api.ProjectLabel:     project.Name,
@@ -82,6 +131,34 @@ func addServiceLabels(project *types.Project) {
 			api.ConfigFilesLabel: strings.Join(project.ComposeFiles, ","),
 			api.OneoffLabel:      "False", // default, will be overridden by `run` command
 		}
+		if s.Name == project.Name {
+			addRouteLabels(s, routes)
+		}
 		project.Services[i] = s
 	}
 }
+
+// addRouteLabels adds the traefik labels routing the hostnames to the ports of the service.
+func addRouteLabels(s types.ServiceConfig, routes []Route) {
+	if len(routes) == 0 {
+		return
+	}
+
+	s.CustomLabels["traefik.enable"] = "true"
+	for network := range s.Networks {
+		s.CustomLabels["traefik.docker.network"] = network
+		break
+	}
+	for i, route := range routes {
+		router := fmt.Sprintf("envme-%s-%d", s.Name, i)
+		s.CustomLabels["traefik.http.routers."+router+".rule"] = fmt.Sprintf("Host(`%s`)", route.Hostname)
+		s.CustomLabels["traefik.http.routers."+router+".entrypoints"] = "web"
+		s.CustomLabels["traefik.http.routers."+router+".service"] = router
+		// The same route over https, with the certificate issued by the envme CA
+		s.CustomLabels["traefik.http.routers."+router+"-tls.rule"] = fmt.Sprintf("Host(`%s`)", route.Hostname)
+		s.CustomLabels["traefik.http.routers."+router+"-tls.entrypoints"] = "websecure"
+		s.CustomLabels["traefik.http.routers."+router+"-tls.tls"] = "true"
+		s.CustomLabels["traefik.http.routers."+router+"-tls.service"] = router
+		s.CustomLabels["traefik.http.services."+router+".loadbalancer.server.port"] = strconv.Itoa(route.Port)
+	}
+}
@@ -0,0 +1,82 @@
+package docker
+
+import (
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/compose/v2/pkg/api"
+	"maps"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAddServiceLabels(t *testing.T) {
+	project := &types.Project{
+		Name:         "web",
+		ComposeFiles: []string{"/home/envme/.envme/web/docker-compose.yaml"},
+		Services: types.Services{
+			"web": {Name: "web", Networks: map[string]*types.ServiceNetworkConfig{"envme": nil}},
+			"db":  {Name: "db", Networks: map[string]*types.ServiceNetworkConfig{"envme": nil}},
+		},
+	}
+	addServiceLabels(project, []Route{
+		{Hostname: "web.localhost", Port: 3000},
+		{Hostname: "admin.localhost", Port: 8080},
+	})
+
+	compose := func(service string) types.Labels {
+		return types.Labels{
+			api.ProjectLabel:     "web",
+			api.ServiceLabel:     service,
+			api.VersionLabel:     api.ComposeVersion,
+			api.WorkingDirLabel:  "/",
+			api.ConfigFilesLabel: "/home/envme/.envme/web/docker-compose.yaml",
+			api.OneoffLabel:      "False",
+		}
+	}
+
+	want := compose("web")
+	maps.Copy(want, map[string]string{
+		"traefik.enable":         "true",
+		"traefik.docker.network": "envme",
+
+		"traefik.http.routers.envme-web-0.rule":                      "Host(`web.localhost`)",
+		"traefik.http.routers.envme-web-0.entrypoints":               "web",
+		"traefik.http.routers.envme-web-0.service":                   "envme-web-0",
+		"traefik.http.routers.envme-web-0-tls.rule":                  "Host(`web.localhost`)",
+		"traefik.http.routers.envme-web-0-tls.entrypoints":           "websecure",
+		"traefik.http.routers.envme-web-0-tls.tls":                   "true",
+		"traefik.http.routers.envme-web-0-tls.service":               "envme-web-0",
+		"traefik.http.services.envme-web-0.loadbalancer.server.port": "3000",
+
+		"traefik.http.routers.envme-web-1.rule":                      "Host(`admin.localhost`)",
+		"traefik.http.routers.envme-web-1.entrypoints":               "web",
+		"traefik.http.routers.envme-web-1.service":                   "envme-web-1",
+		"traefik.http.routers.envme-web-1-tls.rule":                  "Host(`admin.localhost`)",
+		"traefik.http.routers.envme-web-1-tls.entrypoints":           "websecure",
+		"traefik.http.routers.envme-web-1-tls.tls":                   "true",
+		"traefik.http.routers.envme-web-1-tls.service":               "envme-web-1",
+		"traefik.http.services.envme-web-1.loadbalancer.server.port": "8080",
+	})
+	if got := project.Services["web"].CustomLabels; !reflect.DeepEqual(got, want) {
+		t.Errorf("labels of web:\n%v\nwant:\n%v", got, want)
+	}
+
+	// Only the service named after the stack is routed
+	if got := project.Services["db"].CustomLabels; !reflect.DeepEqual(got, compose("db")) {
+		t.Errorf("labels of db = %v, want the compose ones only", got)
+	}
+}
+
+func TestAddServiceLabelsWithoutRoutes(t *testing.T) {
+	project := &types.Project{
+		Name:     "web",
+		Services: types.Services{"web": {Name: "web", Networks: map[string]*types.ServiceNetworkConfig{"envme": nil}}},
+	}
+	addServiceLabels(project, nil)
+
+	for key := range project.Services["web"].CustomLabels {
+		if strings.HasPrefix(key, "traefik.") {
+			t.Errorf("label %s without routes", key)
+		}
+	}
+}
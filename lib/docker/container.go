@@ -0,0 +1,51 @@
+package docker
+
+import (
+	"context"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+	"time"
+)
+
+// ListContainers returns every container, running or not, that belongs to the given compose project.
+func ListContainers(ctx context.Context, client client.APIClient, projectName string) ([]types.Container, error) {
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", api.ProjectLabel+"="+projectName)
+
+	return client.ContainerList(ctx, container.ListOptions{All: true, Filters: filterArgs})
+}
+
+// GetStartedAt returns the time the container was last started.
+func GetStartedAt(ctx context.Context, client client.APIClient, containerID string) (time.Time, error) {
+	info, err := client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Parse(time.RFC3339Nano, info.State.StartedAt)
+}
+
+// ListPublishedPorts returns the running containers publishing ports on the host, of any project.
+func ListPublishedPorts(ctx context.Context, client client.APIClient) ([]types.Container, error) {
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("status", "running")
+
+	containers, err := client.ContainerList(ctx, container.ListOptions{Filters: filterArgs})
+	if err != nil {
+		return nil, err
+	}
+
+	published := containers[:0]
+	for _, c := range containers {
+		for _, p := range c.Ports {
+			if p.PublicPort != 0 {
+				published = append(published, c)
+				break
+			}
+		}
+	}
+	return published, nil
+}
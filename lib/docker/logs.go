@@ -0,0 +1,72 @@
+package docker
+
+import (
+	"fmt"
+	"github.com/charmbracelet/lipgloss"
+	"io"
+	"sync"
+)
+
+var prefixColors = []lipgloss.Color{"6", "3", "2", "5", "4", "1", "14", "11", "10", "13", "12", "9"}
+
+// LogConsumer is an api.LogConsumer writing the container logs of one or more
+// stacks, safe to share between concurrent Logs calls.
+// With prefix set every line starts with its colored container name.
+type LogConsumer struct {
+	mu     sync.Mutex
+	out    io.Writer
+	err    io.Writer
+	prefix bool
+	styles map[string]lipgloss.Style
+	width  int
+}
+
+func NewLogConsumer(out, err io.Writer, prefix bool) *LogConsumer {
+	return &LogConsumer{
+		out:    out,
+		err:    err,
+		prefix: prefix,
+		styles: map[string]lipgloss.Style{},
+	}
+}
+
+func (l *LogConsumer) Register(container string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.register(container)
+}
+
+func (l *LogConsumer) register(container string) {
+	if _, ok := l.styles[container]; ok {
+		return
+	}
+	color := prefixColors[len(l.styles)%len(prefixColors)]
+	l.styles[container] = lipgloss.NewStyle().Foreground(color)
+	l.width = max(l.width, len(container))
+}
+
+func (l *LogConsumer) Log(container, message string) {
+	l.write(l.out, container, message)
+}
+
+func (l *LogConsumer) Err(container, message string) {
+	l.write(l.err, container, message)
+}
+
+func (l *LogConsumer) Status(container, message string) {
+	l.write(l.err, container, message)
+}
+
+func (l *LogConsumer) write(w io.Writer, container, message string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.prefix {
+		_, _ = fmt.Fprintln(w, message)
+		return
+	}
+
+	l.register(container)
+	prefix := fmt.Sprintf("%-*s |", l.width, container)
+	_, _ = fmt.Fprintln(w, l.styles[container].Render(prefix), message)
+}
@@ -4,24 +4,74 @@ import (
 	"context"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
 )
 
-func EnsureNetworkExists(ctx context.Context, client *client.Client, networkName string) error {
+// ManagedLabel marks the Docker resources created by envme.
+const ManagedLabel = "envme.managed"
+
+// NetworkOptions configures the network created by EnsureNetworkExists.
+type NetworkOptions struct {
+	Driver string
+	Subnet string
+	Labels map[string]string
+}
+
+// EnsureNetworkExists creates the network with the given options unless a network
+// with exactly that name already exists.
+func EnsureNetworkExists(ctx context.Context, client client.APIClient, networkName string, options NetworkOptions) error {
+	_, found, err := FindNetwork(ctx, client, networkName)
+	if err != nil || found {
+		return err
+	}
+
+	labels := map[string]string{ManagedLabel: "true"}
+	for k, v := range options.Labels {
+		labels[k] = v
+	}
+	create := types.NetworkCreate{
+		Driver: options.Driver,
+		Labels: labels,
+	}
+	if options.Subnet != "" {
+		create.IPAM = &network.IPAM{Config: []network.IPAMConfig{{Subnet: options.Subnet}}}
+	}
+
+	_, err = client.NetworkCreate(ctx, networkName, create)
+	return err
+}
+
+// FindNetwork returns the network with exactly the given name.
+// The name filter of the Docker API also matches on substrings, so the result is checked.
+func FindNetwork(ctx context.Context, client client.APIClient, networkName string) (types.NetworkResource, bool, error) {
 	filterArgs := filters.NewArgs()
 	filterArgs.Add("name", networkName)
 
 	networks, err := client.NetworkList(ctx, types.NetworkListOptions{Filters: filterArgs})
 	if err != nil {
-		return err
+		return types.NetworkResource{}, false, err
 	}
 
-	if len(networks) == 0 {
-		_, err := client.NetworkCreate(ctx, networkName, types.NetworkCreate{})
-		if err != nil {
-			return err
+	for _, n := range networks {
+		if n.Name == networkName {
+			info, err := client.NetworkInspect(ctx, n.ID, types.NetworkInspectOptions{})
+			return info, err == nil, err
 		}
 	}
 
-	return nil
+	return types.NetworkResource{}, false, nil
+}
+
+// PruneNetworks removes the unused networks created by envme and returns their names.
+func PruneNetworks(ctx context.Context, client client.APIClient) ([]string, error) {
+	filterArgs := filters.NewArgs()
+	filterArgs.Add("label", ManagedLabel+"=true")
+
+	report, err := client.NetworksPrune(ctx, filterArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	return report.NetworksDeleted, nil
 }
@@ -0,0 +1,250 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"golang.org/x/crypto/nacl/secretbox"
+	"golang.org/x/crypto/scrypt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// StoreFile holds the encrypted secrets, under the app directory
+	StoreFile = "secrets.yaml"
+	// KeyFile holds the random key used when no passphrase is given
+	KeyFile = "secret.key"
+	// PassphraseEnv derives the key from a passphrase instead of the key file
+	PassphraseEnv = "ENVME_SECRET_PASSPHRASE"
+	// RefPrefix marks environment values referencing a secret, as in KEY=secret://name
+	RefPrefix = "secret://"
+
+	storeVersion = 1
+	keySize      = 32
+	nonceSize    = 24
+	saltSize     = 16
+)
+
+var nameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+var ErrNotFound = errors.New("secret not found")
+
+type storeFile struct {
+	Version int `yaml:"version"`
+	// Salt of the passphrase key derivation
+	Salt    string            `yaml:"salt,omitempty"`
+	Secrets map[string]string `yaml:"secrets"`
+}
+
+// Store is a set of secrets encrypted with NaCl secretbox, keyed either by a
+// random key file or by a passphrase given through ENVME_SECRET_PASSPHRASE.
+type Store struct {
+	dir  string
+	data storeFile
+	key  *[keySize]byte
+}
+
+// Open loads the secrets stored in the given directory, an empty store when there are none.
+func Open(dir string) (*Store, error) {
+	s := &Store{dir: dir, data: storeFile{Version: storeVersion, Secrets: map[string]string{}}}
+
+	content, err := os.ReadFile(filepath.Join(dir, StoreFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(content, &s.data); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", StoreFile, err)
+	}
+	if s.data.Version > storeVersion {
+		return nil, fmt.Errorf("%s version %d is not supported, please upgrade envme", StoreFile, s.data.Version)
+	}
+	if s.data.Secrets == nil {
+		s.data.Secrets = map[string]string{}
+	}
+	return s, nil
+}
+
+// ValidateName checks that a secret name is usable in a secret:// reference.
+func ValidateName(name string) error {
+	if !nameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q, use letters, digits, '_', '.' or '-'", name)
+	}
+	return nil
+}
+
+// ParseRef returns the secret name of a secret://name value.
+func ParseRef(value string) (string, bool) {
+	name, ok := strings.CutPrefix(value, RefPrefix)
+	return name, ok && name != ""
+}
+
+// Names returns the names of the secrets, sorted.
+func (s *Store) Names() []string {
+	names := make([]string, 0, len(s.data.Secrets))
+	for name := range s.data.Secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether the secret exists.
+func (s *Store) Has(name string) bool {
+	_, ok := s.data.Secrets[name]
+	return ok
+}
+
+// Get decrypts the secret.
+func (s *Store) Get(name string) (string, error) {
+	if !s.Has(name) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+
+	key, err := s.loadKey(false)
+	if err != nil {
+		return "", err
+	}
+	return s.open(name, key)
+}
+
+// open decrypts the secret with the key.
+func (s *Store) open(name string, key *[keySize]byte) (string, error) {
+	box, err := base64.StdEncoding.DecodeString(s.data.Secrets[name])
+	if err != nil || len(box) < nonceSize {
+		return "", fmt.Errorf("secret %s is corrupted", name)
+	}
+
+	var nonce [nonceSize]byte
+	copy(nonce[:], box[:nonceSize])
+	value, ok := secretbox.Open(nil, box[nonceSize:], &nonce, key)
+	if !ok {
+		return "", fmt.Errorf("cannot decrypt secret %s, wrong key or %s", name, PassphraseEnv)
+	}
+	return string(value), nil
+}
+
+// Set encrypts the secret and saves the store.
+func (s *Store) Set(name, value string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+
+	key, err := s.loadKey(true)
+	if err != nil {
+		return err
+	}
+	// A wrong passphrase derives another key, which must not encrypt new secrets
+	if names := s.Names(); len(names) > 0 {
+		if _, err := s.open(names[0], key); err != nil {
+			return err
+		}
+	}
+
+	var nonce [nonceSize]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return err
+	}
+	box := secretbox.Seal(nonce[:], []byte(value), &nonce, key)
+	s.data.Secrets[name] = base64.StdEncoding.EncodeToString(box)
+
+	return s.save()
+}
+
+// Remove deletes the secret and saves the store.
+func (s *Store) Remove(name string) error {
+	if !s.Has(name) {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	delete(s.data.Secrets, name)
+	if len(s.data.Secrets) == 0 {
+		// An empty store can switch between the key file and a passphrase
+		s.data.Salt = ""
+	}
+
+	return s.save()
+}
+
+func (s *Store) save() error {
+	content, err := yaml.Marshal(s.data)
+	if err != nil {
+		return err
+	}
+
+	file := filepath.Join(s.dir, StoreFile)
+	if err := os.WriteFile(file, content, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(file, 0600)
+}
+
+// loadKey derives the key from the passphrase, or reads the key file,
+// generating them when create is set and the store has none yet.
+func (s *Store) loadKey(create bool) (*[keySize]byte, error) {
+	if s.key != nil {
+		return s.key, nil
+	}
+
+	var key [keySize]byte
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		if s.data.Salt == "" {
+			if !create || len(s.data.Secrets) > 0 {
+				return nil, fmt.Errorf("the secrets are encrypted with %s, unset %s", filepath.Join(s.dir, KeyFile), PassphraseEnv)
+			}
+			salt := make([]byte, saltSize)
+			if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+				return nil, err
+			}
+			s.data.Salt = base64.StdEncoding.EncodeToString(salt)
+		}
+		salt, err := base64.StdEncoding.DecodeString(s.data.Salt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid salt in %s", StoreFile)
+		}
+		derived, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, keySize)
+		if err != nil {
+			return nil, err
+		}
+		copy(key[:], derived)
+		s.key = &key
+		return s.key, nil
+	}
+
+	file := filepath.Join(s.dir, KeyFile)
+	if s.data.Salt != "" {
+		return nil, fmt.Errorf("the secrets are encrypted with a passphrase, set %s", PassphraseEnv)
+	}
+	content, err := os.ReadFile(file)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && create:
+		if _, err := io.ReadFull(rand.Reader, key[:]); err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(key[:]) + "\n"
+		if err := os.WriteFile(file, []byte(encoded), 0600); err != nil {
+			return nil, err
+		}
+	case errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("no secret key found at %s, set %s if the secrets use a passphrase", file, PassphraseEnv)
+	case err != nil:
+		return nil, err
+	default:
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(content)))
+		if err != nil || len(decoded) != keySize {
+			return nil, fmt.Errorf("invalid secret key in %s", file)
+		}
+		copy(key[:], decoded)
+	}
+
+	s.key = &key
+	return s.key, nil
+}
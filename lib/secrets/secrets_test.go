@@ -0,0 +1,204 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func openStore(t *testing.T, dir string) *Store {
+	t.Helper()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, passphrase := range []string{"", "correct horse"} {
+		name := "key file"
+		if passphrase != "" {
+			name = "passphrase"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(PassphraseEnv, passphrase)
+			dir := t.TempDir()
+
+			s := openStore(t, dir)
+			if err := s.Set("db_password", "s3cr3t"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Set("api.token", "multi\nline"); err != nil {
+				t.Fatal(err)
+			}
+
+			// A new store reads what the first one saved
+			s = openStore(t, dir)
+			if got := s.Names(); !reflect.DeepEqual(got, []string{"api.token", "db_password"}) {
+				t.Errorf("Names() = %v", got)
+			}
+			for name, want := range map[string]string{"db_password": "s3cr3t", "api.token": "multi\nline"} {
+				got, err := s.Get(name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != want {
+					t.Errorf("Get(%s) = %q, want %q", name, got, want)
+				}
+			}
+
+			if err := s.Remove("db_password"); err != nil {
+				t.Fatal(err)
+			}
+			s = openStore(t, dir)
+			if _, err := s.Get("db_password"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get after Remove = %v, want ErrNotFound", err)
+			}
+			if err := s.Remove("db_password"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("second Remove = %v, want ErrNotFound", err)
+			}
+
+			info, err := os.Stat(filepath.Join(dir, StoreFile))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0600 {
+				t.Errorf("%s mode = %v, want 0600", StoreFile, info.Mode().Perm())
+			}
+			_, err = os.Stat(filepath.Join(dir, KeyFile))
+			if hasKeyFile := err == nil; hasKeyFile != (passphrase == "") {
+				t.Errorf("key file exists = %v with passphrase %q", hasKeyFile, passphrase)
+			}
+		})
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(PassphraseEnv, "right")
+	if err := openStore(t, dir).Set("a", "1"); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(filepath.Join(dir, StoreFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(PassphraseEnv, "wrong")
+	s := openStore(t, dir)
+	if _, err := s.Get("a"); err == nil {
+		t.Error("Get with a wrong passphrase succeeded")
+	}
+	if err := s.Set("b", "2"); err == nil {
+		t.Error("Set with a wrong passphrase succeeded")
+	}
+	after, err := os.ReadFile(filepath.Join(dir, StoreFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(before) != string(after) {
+		t.Error("the store was written with a wrong passphrase")
+	}
+
+	t.Setenv(PassphraseEnv, "right")
+	if got, err := openStore(t, dir).Get("a"); err != nil || got != "1" {
+		t.Errorf("Get = %q, %v, want 1", got, err)
+	}
+}
+
+func TestSwitchKeyMode(t *testing.T) {
+	t.Run("key file to passphrase", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv(PassphraseEnv, "")
+		if err := openStore(t, dir).Set("a", "1"); err != nil {
+			t.Fatal(err)
+		}
+
+		t.Setenv(PassphraseEnv, "pass")
+		s := openStore(t, dir)
+		if err := s.Set("b", "2"); err == nil {
+			t.Error("Set with a passphrase on a key file store succeeded")
+		}
+		if _, err := s.Get("a"); err == nil {
+			t.Error("Get with a passphrase on a key file store succeeded")
+		}
+	})
+
+	t.Run("passphrase to key file", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv(PassphraseEnv, "pass")
+		if err := openStore(t, dir).Set("a", "1"); err != nil {
+			t.Fatal(err)
+		}
+
+		t.Setenv(PassphraseEnv, "")
+		s := openStore(t, dir)
+		if err := s.Set("b", "2"); err == nil {
+			t.Error("Set without passphrase on a passphrase store succeeded")
+		}
+		if _, err := s.Get("a"); err == nil {
+			t.Error("Get without passphrase on a passphrase store succeeded")
+		}
+	})
+
+	t.Run("empty store switches", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv(PassphraseEnv, "pass")
+		s := openStore(t, dir)
+		if err := s.Set("a", "1"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Remove("a"); err != nil {
+			t.Fatal(err)
+		}
+
+		t.Setenv(PassphraseEnv, "")
+		s = openStore(t, dir)
+		if err := s.Set("b", "2"); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := openStore(t, dir).Get("b"); err != nil || got != "2" {
+			t.Errorf("Get = %q, %v, want 2", got, err)
+		}
+	})
+}
+
+func TestWrongKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(PassphraseEnv, "")
+	if err := openStore(t, dir).Set("a", "1"); err != nil {
+		t.Fatal(err)
+	}
+	// Another machine's key
+	other := t.TempDir()
+	if err := openStore(t, other).Set("x", "y"); err != nil {
+		t.Fatal(err)
+	}
+	key, err := os.ReadFile(filepath.Join(other, KeyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, KeyFile), key, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := openStore(t, dir).Set("b", "2"); err == nil {
+		t.Error("Set with a wrong key file succeeded")
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for _, name := range []string{"db", "db_password", "api.token", "A-1"} {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "-a", ".a", "a b", "a/b", "a:b"} {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("ValidateName(%q) succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package templates
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Detection is the framework detected in a project directory and the template matching it.
+type Detection struct {
+	Template  string
+	Framework string
+	Reason    string
+}
+
+type detector func(dir string) *Detection
+
+// detectors run in order, the first match wins
+var detectors = []detector{
+	detectPackageJSON,
+	detectComposerJSON,
+	detectFile("go.mod", "go", "Go"),
+	detectFile("pyproject.toml", "python", "Python"),
+	detectGemfile,
+}
+
+// Detect inspects the project files of dir and returns the matching framework,
+// or nil when none is recognized.
+func Detect(dir string) *Detection {
+	for _, d := range detectors {
+		if detection := d(dir); detection != nil {
+			return detection
+		}
+	}
+	return nil
+}
+
+func detectPackageJSON(dir string) *Detection {
+	var pkg struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil || json.Unmarshal(content, &pkg) != nil {
+		return nil
+	}
+
+	has := func(name string) bool {
+		_, dep := pkg.Dependencies[name]
+		_, dev := pkg.DevDependencies[name]
+		return dep || dev
+	}
+	switch {
+	case has("next"):
+		return &Detection{Template: "nextjs", Framework: "Next.js", Reason: "package.json depends on next"}
+	case has("@nestjs/core"):
+		return &Detection{Template: "nestjs", Framework: "Nest.js", Reason: "package.json depends on @nestjs/core"}
+	}
+	return nil
+}
+
+func detectComposerJSON(dir string) *Detection {
+	var composer struct {
+		Require map[string]string `json:"require"`
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "composer.json"))
+	if err != nil || json.Unmarshal(content, &composer) != nil {
+		return nil
+	}
+
+	if _, ok := composer.Require["laravel/framework"]; ok {
+		return &Detection{Template: "laravel", Framework: "Laravel", Reason: "composer.json requires laravel/framework"}
+	}
+	return nil
+}
+
+func detectGemfile(dir string) *Detection {
+	file, err := os.Open(filepath.Join(dir, "Gemfile"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, `gem "rails"`) || strings.HasPrefix(line, `gem 'rails'`) {
+			return &Detection{Template: "rails", Framework: "Rails", Reason: "Gemfile requires rails"}
+		}
+	}
+	return &Detection{Template: "ruby", Framework: "Ruby", Reason: "Gemfile found"}
+}
+
+func detectFile(name, template, framework string) detector {
+	return func(dir string) *Detection {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return nil
+		}
+		return &Detection{Template: template, Framework: framework, Reason: name + " found"}
+	}
+}
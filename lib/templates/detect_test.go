@@ -0,0 +1,132 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name:  "next in dependencies",
+			files: map[string]string{"package.json": `{"dependencies": {"next": "14.2.0", "react": "18.3.0"}}`},
+			want:  "nextjs",
+		},
+		{
+			name:  "nest in devDependencies",
+			files: map[string]string{"package.json": `{"devDependencies": {"@nestjs/core": "^10.0.0"}}`},
+			want:  "nestjs",
+		},
+		{
+			name:  "laravel in composer.json",
+			files: map[string]string{"composer.json": `{"require": {"php": "^8.2", "laravel/framework": "^11.0"}}`},
+			want:  "laravel",
+		},
+		{
+			name:  "go.mod",
+			files: map[string]string{"go.mod": "module example.com/api\n"},
+			want:  "go",
+		},
+		{
+			name:  "pyproject.toml",
+			files: map[string]string{"pyproject.toml": "[project]\nname = \"api\"\n"},
+			want:  "python",
+		},
+		{
+			name:  "Gemfile with rails",
+			files: map[string]string{"Gemfile": "source \"https://rubygems.org\"\n\n  gem 'rails', '~> 7.1'\n"},
+			want:  "rails",
+		},
+		{
+			name:  "Gemfile without rails",
+			files: map[string]string{"Gemfile": "source \"https://rubygems.org\"\ngem \"sinatra\"\n# gem \"rails\"\n"},
+			want:  "ruby",
+		},
+		{
+			name: "package.json before composer.json",
+			files: map[string]string{
+				"package.json":  `{"dependencies": {"next": "14.2.0"}}`,
+				"composer.json": `{"require": {"laravel/framework": "^11.0"}}`,
+				"go.mod":        "module example.com/api\n",
+			},
+			want: "nextjs",
+		},
+		{
+			name: "next before nest",
+			files: map[string]string{
+				"package.json": `{"dependencies": {"@nestjs/core": "^10.0.0"}, "devDependencies": {"next": "14.2.0"}}`,
+			},
+			want: "nextjs",
+		},
+		{
+			name: "laravel before go.mod",
+			files: map[string]string{
+				"composer.json": `{"require": {"laravel/framework": "^11.0"}}`,
+				"go.mod":        "module example.com/api\n",
+			},
+			want: "laravel",
+		},
+		{
+			name: "go.mod before pyproject.toml and Gemfile",
+			files: map[string]string{
+				"go.mod":         "module example.com/api\n",
+				"pyproject.toml": "[project]\n",
+				"Gemfile":        "gem \"rails\"\n",
+			},
+			want: "go",
+		},
+		{
+			name: "package.json of another framework",
+			files: map[string]string{
+				"package.json":   `{"dependencies": {"express": "^4.0.0"}}`,
+				"pyproject.toml": "[project]\n",
+			},
+			want: "python",
+		},
+		{
+			name: "malformed package.json",
+			files: map[string]string{
+				"package.json": `{"dependencies": {"next": `,
+				"Gemfile":      "gem \"rails\"\n",
+			},
+			want: "rails",
+		},
+		{
+			name:  "malformed package.json only",
+			files: map[string]string{"package.json": `{"dependencies": ["next"]}`},
+		},
+		{
+			name:  "laravel not required",
+			files: map[string]string{"composer.json": `{"require-dev": {"laravel/framework": "^11.0"}}`},
+		},
+		{
+			name: "nothing to detect",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := Detect(dir)
+			switch {
+			case got == nil && tt.want != "":
+				t.Errorf("Detect() = nil, want %s", tt.want)
+			case got != nil && got.Template != tt.want:
+				t.Errorf("Detect() = %s (%s), want %q", got.Template, got.Reason, tt.want)
+			case got != nil && (got.Framework == "" || got.Reason == ""):
+				t.Errorf("Detect() = %+v, want a framework and a reason", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"envme/lib/types"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	// ManifestFile describes a user template, next to its Dockerfile
+	ManifestFile   = "template.yaml"
+	DockerfileFile = "Dockerfile"
+	// SourceBuiltin is the source of the templates embedded in envme
+	SourceBuiltin = "builtin"
+)
+
+//go:embed dockerfiles/*.Dockerfile
+var dockerfiles embed.FS
+
+// Template is a Dockerfile template with a development target, rendered with
+// text/template from its parameters. User templates are a directory holding a
+// template.yaml manifest and the Dockerfile.
+type Template struct {
+	Name        string            `yaml:"-"`
+	Title       string            `yaml:"title"`
+	Description string            `yaml:"description,omitempty"`
+	Port        int               `yaml:"port,omitempty"`
+	Params      map[string]string `yaml:"params,omitempty"`
+	// Compose is merged into the service generated for the development environment
+	Compose *types.Service `yaml:"compose,omitempty"`
+	// Source is SourceBuiltin or the directory of a user template
+	Source string `yaml:"-"`
+}
+
+var builtins = []*Template{
+	{
+		Name:   "nextjs",
+		Title:  "Next.js",
+		Port:   3000,
+		Params: map[string]string{"node_version": "20"},
+		Source: SourceBuiltin,
+	},
+	{
+		Name:   "nestjs",
+		Title:  "Nest.js",
+		Port:   3000,
+		Params: map[string]string{"node_version": "20"},
+		Source: SourceBuiltin,
+	},
+	{
+		Name:   "laravel",
+		Title:  "Laravel",
+		Port:   8000,
+		Params: map[string]string{"php_version": "8.3"},
+		Source: SourceBuiltin,
+	},
+}
+
+// Load returns the builtin templates followed by the ones found in the given
+// directories. A template overrides any earlier one with the same name.
+func Load(dirs ...string) ([]*Template, error) {
+	list := append([]*Template{}, builtins...)
+
+	for _, dir := range dirs {
+		manifests, err := filepath.Glob(filepath.Join(dir, "*", ManifestFile))
+		if err != nil {
+			return nil, err
+		}
+		for _, manifest := range manifests {
+			t, err := loadTemplate(filepath.Dir(manifest))
+			if err != nil {
+				return nil, err
+			}
+			list = replace(list, t)
+		}
+	}
+	return list, nil
+}
+
+func replace(list []*Template, t *Template) []*Template {
+	for i, existing := range list {
+		if existing.Name == t.Name {
+			list[i] = t
+			return list
+		}
+	}
+	return append(list, t)
+}
+
+func loadTemplate(dir string) (*Template, error) {
+	content, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Template{}
+	if err := yaml.Unmarshal(content, t); err != nil {
+		return nil, fmt.Errorf("invalid template manifest %s: %w", filepath.Join(dir, ManifestFile), err)
+	}
+	t.Name = filepath.Base(dir)
+	t.Source = dir
+	if t.Title == "" {
+		t.Title = t.Name
+	}
+	return t, nil
+}
+
+// Find returns the template matching the name or title, ignoring case.
+func Find(name string, dirs ...string) (*Template, error) {
+	list, err := Load(dirs...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range list {
+		if strings.EqualFold(t.Name, name) || strings.EqualFold(t.Title, name) {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown template %q, see `envme template list`", name)
+}
+
+// Defaults returns the parameters of the template with their default values,
+// including the port.
+func (t *Template) Defaults() map[string]string {
+	values := make(map[string]string, len(t.Params)+1)
+	for k, v := range t.Params {
+		values[k] = v
+	}
+	if t.Port > 0 {
+		values["port"] = strconv.Itoa(t.Port)
+	}
+	return values
+}
+
+// ParamNames returns the sorted names of the template parameters.
+func (t *Template) ParamNames() []string {
+	defaults := t.Defaults()
+	names := make([]string, 0, len(defaults))
+	for k := range defaults {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Dockerfile returns the unrendered Dockerfile of the template.
+func (t *Template) Dockerfile() ([]byte, error) {
+	if t.Source == SourceBuiltin {
+		return dockerfiles.ReadFile("dockerfiles/" + t.Name + ".Dockerfile")
+	}
+	return os.ReadFile(filepath.Join(t.Source, DockerfileFile))
+}
+
+// Render returns the Dockerfile of the template with the given parameters
+// overriding its defaults.
+func (t *Template) Render(params map[string]string) ([]byte, error) {
+	values := t.Defaults()
+	for k, v := range params {
+		if _, ok := values[k]; !ok {
+			return nil, fmt.Errorf("unknown parameter %q for template %s, expected one of %s", k, t.Name, strings.Join(t.ParamNames(), ", "))
+		}
+		values[k] = v
+	}
+
+	content, err := t.Dockerfile()
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(t.Name).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Create writes a new user template into dir, copying the manifest and
+// Dockerfile of from when given, or a skeleton otherwise.
+func Create(dir, name string, from *Template) (*Template, error) {
+	target := filepath.Join(dir, name)
+	if _, err := os.Stat(target); err == nil {
+		return nil, fmt.Errorf("template %s already exists", target)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	t := &Template{Title: name, Port: 8080, Params: map[string]string{}}
+	dockerfile := []byte(skeleton)
+	if from != nil {
+		t = &Template{
+			Title:       name,
+			Description: from.Description,
+			Port:        from.Port,
+			Params:      from.Params,
+			Compose:     from.Compose,
+		}
+		content, err := from.Dockerfile()
+		if err != nil {
+			return nil, err
+		}
+		dockerfile = content
+	}
+
+	manifest, err := yaml.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(target, ManifestFile), manifest, 0644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(target, DockerfileFile), dockerfile, 0644); err != nil {
+		return nil, err
+	}
+
+	t.Name = name
+	t.Source = target
+	return t, nil
+}
+
+const skeleton = `# syntax=docker/dockerfile:1
+# Parameters from template.yaml are available as {{ "{{ .name }}" }}, the port as {{ "{{ .port }}" }}.
+
+FROM alpine:3 AS base
+WORKDIR /app
+
+FROM base AS development
+COPY . .
+EXPOSE {{ .port }}
+CMD ["sh"]
+`
+
+// ParseParams parses parameters in the format <key>=<value>.
+func ParseParams(specs []string) (map[string]string, error) {
+	params := make(map[string]string, len(specs))
+	for _, spec := range specs {
+		key, value, ok := strings.Cut(spec, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid parameter %q, expected <key>=<value>", spec)
+		}
+		params[key] = value
+	}
+	return params, nil
+}
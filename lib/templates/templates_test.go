@@ -0,0 +1,110 @@
+package templates
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestRender(t *testing.T) {
+	overrides := map[string]map[string]string{
+		"nextjs":  {"node_version": "22", "port": "4000"},
+		"nestjs":  {"node_version": "18", "port": "4000"},
+		"laravel": {"php_version": "8.2", "port": "9000"},
+	}
+
+	for _, name := range []string{"nextjs", "nestjs", "laravel"} {
+		tmpl, err := Find(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Run(name+"/defaults", func(t *testing.T) {
+			got, err := tmpl.Render(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, name+".golden", got)
+		})
+
+		t.Run(name+"/overrides", func(t *testing.T) {
+			got, err := tmpl.Render(overrides[name])
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertGolden(t, name+".override.golden", got)
+		})
+	}
+}
+
+func TestRenderUnknownParam(t *testing.T) {
+	tmpl, err := Find("nextjs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tmpl.Render(map[string]string{"php_version": "8.3"})
+	if err == nil {
+		t.Fatal("expected an error for an unknown parameter")
+	}
+	if !strings.Contains(err.Error(), `unknown parameter "php_version"`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func assertGolden(t *testing.T, file string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", file)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("render does not match %s, run with -update to refresh it\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
+
+func TestLoadUserTemplate(t *testing.T) {
+	manifests := map[string]string{
+		"strings": "compose:\n  command: uvicorn main:app --host 0.0.0.0\n  entrypoint: /entrypoint.sh\n  healthcheck:\n    test: curl -f http://localhost:8000\n",
+		"lists":   "compose:\n  command: [\"uvicorn\", \"main:app\", \"--host\", \"0.0.0.0\"]\n  entrypoint: [\"/entrypoint.sh\"]\n  healthcheck:\n    test: [\"CMD-SHELL\", \"curl -f http://localhost:8000\"]\n",
+	}
+
+	dir := t.TempDir()
+	for name, manifest := range manifests {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name, ManifestFile), []byte(manifest), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for name := range manifests {
+		tmpl, err := Find(name, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compose := tmpl.Compose
+		if got := strings.Join(compose.Command, " "); got != "uvicorn main:app --host 0.0.0.0" {
+			t.Errorf("%s: command = %q", name, compose.Command)
+		}
+		if got := strings.Join(compose.Entrypoint, " "); got != "/entrypoint.sh" {
+			t.Errorf("%s: entrypoint = %q", name, compose.Entrypoint)
+		}
+		if got := strings.Join(compose.Healthcheck.Test, "|"); got != "CMD-SHELL|curl -f http://localhost:8000" {
+			t.Errorf("%s: healthcheck test = %q", name, compose.Healthcheck.Test)
+		}
+	}
+}
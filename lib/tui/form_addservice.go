@@ -0,0 +1,194 @@
+package tui
+
+import (
+	"context"
+	"envme/lib/utils"
+	"envme/pkg/envme"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/viper"
+	"strings"
+)
+
+type AddServiceForm struct {
+	Model
+	compose string
+
+	Stack   string
+	Service string
+	Image   string
+	Env     string
+	Publish string
+}
+
+func NewAddServiceForm() AddServiceForm {
+	m := AddServiceForm{
+		Model: NewModel(0),
+	}
+	stacks, _ := envme.UserStacks()
+	m.lg = lipgloss.DefaultRenderer()
+	m.styles = NewStyles(m.lg)
+
+	m.form = huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Key("stack").
+				Title("Stack").
+				Options(huh.NewOptions(stacks...)...).
+				Value(&m.Stack).
+				Validate(
+					VSave("stack"),
+				),
+
+			huh.NewInput().
+				Key("service").
+				Title("Service name").
+				Placeholder("db").
+				Value(&m.Service).
+				Validate(
+					VRequiredAndSave("service", "Service name is required"),
+				),
+
+			huh.NewInput().
+				Key("image").
+				Title("Image name").
+				Placeholder("postgres:16").
+				Value(&m.Image).
+				Validate(
+					VRequiredAndSave("image", "Image name is required"),
+				),
+
+			huh.NewText().
+				Key("env").
+				Title("Environment").
+				Placeholder(`POSTGRES_PASSWORD=secret://db`).
+				Editor("nano").
+				Value(&m.Env).
+				Validate(
+					VLinesAndSave("env"),
+				),
+
+			huh.NewText().
+				Key("publish").
+				Title("Publish").
+				Placeholder(`5432:5432`).
+				Editor("nano").
+				Value(&m.Publish).
+				Validate(
+					VLinesAndSave("publish"),
+				).
+				Lines(2),
+
+			huh.NewConfirm().
+				Key("done").
+				Title("All done?").
+				Validate(VDoneAndValidate("stack", func() ([]byte, error) {
+					return envme.PreviewSidecar(context.Background(), viper.GetString("stack"), viper.GetString("service"),
+						viper.GetString("image"), viper.GetString("network"), viper.GetStringSlice("publish"))
+				})).
+				Affirmative("Yep").
+				Negative("Wait, no"),
+		),
+	).
+		WithWidth(55).
+		WithShowHelp(false).
+		WithShowErrors(false)
+	return m
+}
+
+func (m AddServiceForm) View() string {
+	s := m.styles
+
+	switch m.form.State {
+	case huh.StateCompleted:
+		return s.Status.Copy().Margin(0, 1).Width(50).Render(viper.GetString("compose")) + "\n\n"
+	default:
+		// Form (left side)
+		v := strings.TrimSuffix(m.form.View(), "\n\n")
+		form := m.lg.NewStyle().Margin(1, 0).Render(v)
+
+		// Status (right side)
+		var status string
+		{
+			stack := m.form.GetString("stack")
+			service := m.form.GetString("service")
+			m.compose = s.Help.Render(stack+"/docker-compose.yaml") + "\n\n"
+
+			var (
+				content []byte
+				err     error
+			)
+			if service != "" {
+				content, err = envme.PreviewSidecar(context.Background(), stack, service, m.form.GetString("image"),
+					viper.GetString("network"), splitLines(m.form.GetString("publish")))
+			} else {
+				content, err = utils.ReadComposeFile(stack)
+			}
+			if err == nil {
+				m.compose += highlightYAML(string(content), s)
+			} else {
+				m.compose += s.Help.Render(err.Error())
+			}
+			if service != "" {
+				m.compose += envView(utils.ServiceEnvFile(stack, service), splitLines(m.form.GetString("env")), s)
+			}
+
+			viper.Set("compose", m.compose)
+
+			const statusWidth = 50
+			statusMarginLeft := m.width - statusWidth - lipgloss.Width(form) - s.Status.GetMarginRight()
+			status = s.Status.Copy().
+				Height(lipgloss.Height(form)).
+				Width(statusWidth).
+				MarginLeft(statusMarginLeft).
+				Render(m.compose)
+		}
+
+		errors := m.form.Errors()
+		header := m.appBoundaryView("Add Service Form")
+		if len(errors) > 0 {
+			header = m.appErrorBoundaryView(m.errorView())
+		}
+		body := lipgloss.JoinHorizontal(lipgloss.Top, form, status)
+
+		footer := m.appBoundaryView(m.form.Help().ShortHelpView(m.form.KeyBinds()))
+		if len(errors) > 0 {
+			footer = m.appErrorBoundaryView("")
+		}
+
+		return s.Base.Render(header + "\n" + body + "\n\n" + footer)
+	}
+}
+
+func (m AddServiceForm) Init() tea.Cmd {
+	return m.form.Init()
+}
+
+func (m AddServiceForm) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = min(msg.Width, maxWidth) - m.styles.Base.GetHorizontalFrameSize()
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "esc", "ctrl+c":
+			return m, tea.Quit
+		}
+	}
+
+	var commands []tea.Cmd
+
+	// Process the form
+	form, cmd := m.form.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.form = f
+		commands = append(commands, cmd)
+	}
+
+	if m.form.State == huh.StateCompleted {
+		// Quit when the form is done.
+		commands = append(commands, tea.Quit)
+	}
+
+	return m, tea.Batch(commands...)
+}
@@ -1,17 +1,23 @@
 package tui
 
 import (
-	"fmt"
+	"context"
+	"envme/lib/utils"
+	"envme/pkg/envme"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/viper"
+	"slices"
 	"strings"
 )
 
 type DevelopmentForm struct {
 	Model
 	compose string
+	// templates lists the templates visible from templatesDir, the entered directory
+	templates    *huh.Select[string]
+	templatesDir string
 
 	ContainerName string
 	Dir           string
@@ -27,6 +33,15 @@ func NewDevelopmentForm() DevelopmentForm {
 	m.lg = lipgloss.DefaultRenderer()
 	m.styles = NewStyles(m.lg)
 
+	m.templates = huh.NewSelect[string]().
+		Key("template").
+		Title("Template (Dockerfile)").
+		Options(templateOptions("")...).
+		Value(&m.Template).
+		Validate(
+			VSave("template"),
+		).Description("Select (none) if you already have a Dockerfile")
+
 	m.form = huh.NewForm(
 		huh.NewGroup(
 			huh.NewInput().
@@ -54,7 +69,7 @@ func NewDevelopmentForm() DevelopmentForm {
 				Editor("nano").
 				Value(&m.Env).
 				Validate(
-					VSave("env"),
+					VLinesAndSave("env"),
 				),
 
 			huh.NewText().
@@ -64,28 +79,23 @@ func NewDevelopmentForm() DevelopmentForm {
 				Editor("nano").
 				Value(&m.Expose).
 				Validate(
-					VSave("expose"),
+					VExposeAndSave("expose"),
 				).
 				Lines(2),
 
-			huh.NewSelect[string]().
-				Key("template").
-				Title("Template (Dockerfile)").
-				Options(huh.NewOptions("(none)", "Next.js", "Nest.js", "Laravel")...).
-				Value(&m.Template).
-				Validate(
-					VSave("template"),
-				).Description("Select (none) if you already have a Dockerfile"),
+			m.templates,
 
 			huh.NewConfirm().
 				Key("done").
 				Title("All done?").
-				Validate(func(v bool) error {
-					if !v {
-						return fmt.Errorf("Welp, finish up then")
+				Validate(VDoneAndValidate("container_name", func() ([]byte, error) {
+					dir, err := utils.GetAbsPath(viper.GetString("dir"))
+					if err != nil {
+						return nil, err
 					}
-					return nil
-				}).
+					return envme.PreviewDev(context.Background(), viper.GetString("container_name"), dir,
+						viper.GetString("template"), viper.GetString("network"), viper.GetStringSlice("publish"))
+				})).
 				Affirmative("Yep").
 				Negative("Wait, no"),
 		),
@@ -96,6 +106,32 @@ func NewDevelopmentForm() DevelopmentForm {
 	return m
 }
 
+// templateOptions lists the Dockerfile templates visible from the project
+// directory, (none) keeps the existing Dockerfile
+func templateOptions(dir string) []huh.Option[string] {
+	options := []huh.Option[string]{huh.NewOption("(none)", "")}
+	list, _ := envme.LoadTemplates(dir)
+	for _, t := range list {
+		options = append(options, huh.NewOption(t.Title, t.Name))
+	}
+	return options
+}
+
+// detectionView describes the Dockerfile that will be used for the directory
+func detectionView(dir string) string {
+	if utils.HasDockerfile(dir) {
+		return "# using the existing Dockerfile"
+	}
+	detection, t := envme.DetectTemplate(dir)
+	switch {
+	case detection == nil:
+		return "# no Dockerfile, no framework detected"
+	case t == nil:
+		return "# detected " + detection.Framework + ", no " + detection.Template + " template"
+	}
+	return "# detected " + detection.Framework + ", template " + t.Name
+}
+
 func (m DevelopmentForm) View() string {
 	s := m.styles
 
@@ -110,62 +146,29 @@ func (m DevelopmentForm) View() string {
 		// Status (right side)
 		var status string
 		{
-			const (
-				t = "  " // tab
-				n = "\n" // end of line
-			)
-			var (
-				header    = s.Help.Render("docker-compose.yaml")
-				service   = s.Help.Render("(none)")
-				build     = s.Help.Render("(none)")
-				additions = ""
-				container = ""
-			)
-
+			header := s.Help.Render("docker-compose.yaml")
 			containerName := m.form.GetString("container_name")
 			if containerName != "" {
-				header = s.Help.Render(containerName + "/" + header)
-				service = s.Highlight.Render(containerName) + ":"
-			} else {
-				build = ""
+				header = s.Help.Render(containerName + "/docker-compose.yaml")
 			}
 
-			dir := m.form.GetString("dir")
-			envValue := m.form.GetString("env")
-			if dir != "" {
-				build = "build:" + n
-				build += t + t + t + "context: " + s.Highlight.Render(dir) + n
-				build += t + t + t + "target: " + s.Highlight.Render("development")
-				container = "container_name: " + s.Highlight.Render(containerName)
-				additions += t + t + "restart: unless-stopped" + n
-				if envValue != "" {
-					additions += t + t + "environment:" + n
-					envs := strings.Split(envValue, n)
-					for _, env := range envs {
-						additions += t + t + t + "- " + s.Highlight.Render(env) + n
-					}
-				}
-				additions += t + t + "networks:" + n
-				additions += t + t + t + "- envme" + n
-				additions += t + t + "extra_hosts:" + n
-				additions += t + t + t + "- host.docker.internal:host-gateway" + n
-				additions += t + t + "volumes:" + n
-				additions += t + t + t + "- " + s.Highlight.Render(dir) + ":/app" + n
-				additions += n
-				additions += "networks:" + n
-				additions += t + "envme:" + n
-				additions += t + t + "external: true" + n
+			dir, _ := utils.GetAbsPath(m.form.GetString("dir"))
+			template := m.form.GetString("template")
+			if dir != "" && template == "" {
+				header += "\n" + s.Help.Render(detectionView(dir))
 			}
 
-			m.compose += header + n
-			m.compose += n
-			m.compose += "version: '3.8'" + n
-			m.compose += n
-			m.compose += "services:" + n
-			m.compose += t + service + n
-			m.compose += t + t + build + n
-			m.compose += t + t + container + n
-			m.compose += additions
+			m.compose = header + "\n\n"
+			if containerName != "" {
+				content, err := envme.PreviewDev(context.Background(), containerName, dir, template,
+					viper.GetString("network"), viper.GetStringSlice("publish"))
+				if err == nil {
+					m.compose += highlightYAML(string(content), s)
+				} else {
+					m.compose += s.Help.Render(err.Error())
+				}
+			}
+			m.compose += envView(utils.EnvFile, splitLines(m.form.GetString("env")), s)
 
 			viper.Set("compose", m.compose)
 
@@ -218,6 +221,19 @@ func (m DevelopmentForm) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		commands = append(commands, cmd)
 	}
 
+	// The project templates of the entered directory are selectable, as on the CLI
+	if dir, _ := utils.GetAbsPath(m.form.GetString("dir")); dir != m.templatesDir {
+		m.templatesDir = dir
+		options := templateOptions(dir)
+		m.templates.Options(options...)
+		selected := m.form.GetString("template")
+		if !slices.ContainsFunc(options, func(o huh.Option[string]) bool { return o.Value == selected }) {
+			none := ""
+			m.templates.Value(&none)
+			viper.Set("template", none)
+		}
+	}
+
 	if m.form.State == huh.StateCompleted {
 		// Quit when the form is done.
 		commands = append(commands, tea.Quit)
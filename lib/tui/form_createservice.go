@@ -1,7 +1,10 @@
 package tui
 
 import (
-	"fmt"
+	"context"
+	"envme/lib/catalog"
+	"envme/lib/utils"
+	"envme/pkg/envme"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
@@ -14,6 +17,7 @@ type ServiceForm struct {
 	compose string
 
 	ContainerName string
+	From          string
 	Image         string
 	Env           string
 	Expose        string
@@ -37,14 +41,23 @@ func NewServiceForm() ServiceForm {
 					VRequiredAndSave("container_name", "Service name is required"),
 				),
 
+			huh.NewSelect[string]().
+				Key("from").
+				Title("From catalog").
+				Options(catalogOptions()...).
+				Value(&m.From).
+				Validate(
+					VSave("from"),
+				).Description("Select (none) to run your own image"),
+
 			huh.NewInput().
 				Key("image").
 				Title("Image name").
 				Placeholder("backend:latest").
 				Value(&m.Image).
 				Validate(
-					VRequiredAndSave("image", "Image name is required"),
-				),
+					VImageAndSave("image", "from"),
+				).Description("Leave empty to use the image of the catalog service"),
 
 			huh.NewText().
 				Key("env").
@@ -53,7 +66,7 @@ func NewServiceForm() ServiceForm {
 				Editor("nano").
 				Value(&m.Env).
 				Validate(
-					VSave("env"),
+					VLinesAndSave("env"),
 				),
 
 			huh.NewText().
@@ -63,19 +76,17 @@ func NewServiceForm() ServiceForm {
 				Editor("nano").
 				Value(&m.Expose).
 				Validate(
-					VSave("expose"),
+					VExposeAndSave("expose"),
 				).
 				Lines(2),
 
 			huh.NewConfirm().
 				Key("done").
 				Title("All done?").
-				Validate(func(v bool) error {
-					if !v {
-						return fmt.Errorf("Welp, finish up then")
-					}
-					return nil
-				}).
+				Validate(VDoneAndValidate("container_name", func() ([]byte, error) {
+					return envme.PreviewService(context.Background(), viper.GetString("container_name"), viper.GetString("image"),
+						viper.GetString("network"), viper.GetString("from"), viper.GetStringSlice("publish"))
+				})).
 				Affirmative("Yep").
 				Negative("Wait, no"),
 		),
@@ -86,6 +97,16 @@ func NewServiceForm() ServiceForm {
 	return m
 }
 
+// catalogOptions lists the catalog services, (none) runs the given image
+func catalogOptions() []huh.Option[string] {
+	options := []huh.Option[string]{huh.NewOption("(none)", "")}
+	list, _ := catalog.List()
+	for _, e := range list {
+		options = append(options, huh.NewOption(e.Title, e.Name))
+	}
+	return options
+}
+
 func (m ServiceForm) View() string {
 	s := m.styles
 
@@ -100,58 +121,29 @@ func (m ServiceForm) View() string {
 		// Status (right side)
 		var status string
 		{
-			const (
-				t = "  " // tab
-				n = "\n" // end of line
-			)
-			var (
-				header    = s.Help.Render("docker-compose.yaml")
-				service   = s.Help.Render("(none)")
-				image     = s.Help.Render("(none)")
-				additions = ""
-				container = ""
-			)
-
+			header := s.Help.Render("docker-compose.yaml")
 			containerName := m.form.GetString("container_name")
 			if containerName != "" {
-				header = s.Help.Render(containerName + "/" + header)
-				service = s.Highlight.Render(containerName) + ":"
-			} else {
-				image = ""
+				header = s.Help.Render(containerName + "/docker-compose.yaml")
 			}
 
-			imageName := m.form.GetString("image")
-			envValue := m.form.GetString("env")
-			if imageName != "" {
-				image = "image: " + s.Highlight.Render(imageName)
-				container = "container_name: " + s.Highlight.Render(containerName)
-				additions += t + t + "restart: unless-stopped" + n
-				if envValue != "" {
-					additions += t + t + "environment:" + n
-					envs := strings.Split(envValue, n)
-					for _, env := range envs {
-						additions += t + t + t + "- " + s.Highlight.Render(env) + n
-					}
+			m.compose = header + "\n\n"
+			entry, _ := catalog.Find(m.form.GetString("from"))
+			if containerName != "" {
+				content, err := envme.PreviewService(context.Background(), containerName, m.form.GetString("image"),
+					viper.GetString("network"), m.form.GetString("from"), viper.GetStringSlice("publish"))
+				if err == nil {
+					m.compose += highlightYAML(string(content), s)
+				} else {
+					m.compose += s.Help.Render(err.Error())
 				}
-				additions += t + t + "networks:" + n
-				additions += t + t + t + "- envme" + n
-				additions += t + t + "extra_hosts:" + n
-				additions += t + t + t + "- host.docker.internal:host-gateway" + n
-				additions += n
-				additions += "networks:" + n
-				additions += t + "envme:" + n
-				additions += t + t + "external: true" + n
 			}
-
-			m.compose += header + n
-			m.compose += n
-			m.compose += "version: '3.8'" + n
-			m.compose += n
-			m.compose += "services:" + n
-			m.compose += t + service + n
-			m.compose += t + t + image + n
-			m.compose += t + t + container + n
-			m.compose += additions
+			env := splitLines(m.form.GetString("env"))
+			if entry != nil {
+				// Generated passwords are only known once the service is created
+				env = append(append(append([]string{}, entry.Env...), generatedView(entry)...), env...)
+			}
+			m.compose += envView(utils.EnvFile, env, s)
 
 			viper.Set("compose", m.compose)
 
@@ -211,3 +203,12 @@ func (m ServiceForm) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	return m, tea.Batch(commands...)
 }
+
+// generatedView shows the variables that get a random password on creation
+func generatedView(entry *catalog.Entry) []string {
+	var lines []string
+	for _, key := range entry.Generate {
+		lines = append(lines, key+"=<generated>")
+	}
+	return lines
+}
@@ -1,11 +1,13 @@
 package tui
 
 import (
+	"envme/pkg/envme"
 	"fmt"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
 	"strings"
 )
 
@@ -22,6 +24,7 @@ func NewExposeForm() ExposeForm {
 	m := ExposeForm{
 		Model: NewModel(0),
 	}
+	services, _ := envme.UserStacks()
 	m.lg = lipgloss.DefaultRenderer()
 	m.styles = NewStyles(m.lg)
 
@@ -30,7 +33,7 @@ func NewExposeForm() ExposeForm {
 			huh.NewSelect[string]().
 				Key("container_name").
 				Title("Service name").
-				Options(huh.NewOptions("cloudflared")...). // TODO: Get list of services from appDir
+				Options(huh.NewOptions(services...)...).
 				Value(&m.ContainerName).
 				Validate(
 					VSave("container_name"),
@@ -87,26 +90,25 @@ func (m ExposeForm) View() string {
 		// Status (right side)
 		var status string
 		{
-			const (
-				t = "  " // tab
-				n = "\n" // end of line
-			)
 			var (
-				header = s.Help.Render("config.yaml")
+				header = s.Help.Render(envme.TunnelStack + "/config.yaml")
 			)
 
 			containerName := m.form.GetString("container_name")
 			port := m.form.GetString("port")
 			hostname := m.form.GetString("hostname")
 
-			m.compose += header + n
-			m.compose += n
-			m.compose += "tunnel: localstack" + n
-			m.compose += "credentials-file: credentials.json" + n
-			m.compose += "ingress:" + n
-			m.compose += t + "- hostname: " + s.Highlight.Render(hostname) + n
-			m.compose += t + t + "service: " + s.Highlight.Render("http://"+containerName+":"+port) + n
-			m.compose += t + "- service: http_status:404"
+			m.compose = header + "\n\n"
+			config, err := envme.ReadTunnelConfig()
+			if err == nil {
+				if rule, err := envme.NewExposeRule(port, hostname); err == nil {
+					envme.MergeIngress(config, envme.NewIngressRule(containerName, rule))
+				}
+				content, _ := yaml.Marshal(config)
+				m.compose += string(content)
+			} else {
+				m.compose += s.Help.Render(err.Error())
+			}
 
 			viper.Set("compose", m.compose)
 
@@ -120,7 +122,7 @@ func (m ExposeForm) View() string {
 		}
 
 		errors := m.form.Errors()
-		header := m.appBoundaryView("Create Service Form")
+		header := m.appBoundaryView("Expose Service Form")
 		if len(errors) > 0 {
 			header = m.appErrorBoundaryView(m.errorView())
 		}
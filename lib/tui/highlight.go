@@ -0,0 +1,45 @@
+package tui
+
+import (
+	"envme/lib/utils"
+	"strings"
+)
+
+// highlightYAML colors the keys and values of a YAML document line by line.
+func highlightYAML(content string, s *Styles) string {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	for i, line := range lines {
+		indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+		rest := strings.TrimPrefix(line, indent)
+
+		prefix := ""
+		if strings.HasPrefix(rest, "- ") {
+			prefix = "- "
+			rest = strings.TrimPrefix(rest, "- ")
+		}
+
+		key, value, ok := strings.Cut(rest, ":")
+		if ok && !strings.Contains(key, " ") && (value == "" || strings.HasPrefix(value, " ")) {
+			lines[i] = indent + prefix + s.StatusHeader.Render(key) + ":" + s.Highlight.Render(value)
+			continue
+		}
+		lines[i] = indent + prefix + s.Highlight.Render(rest)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// envView renders the env file written for the environment lines of a form.
+func envView(file string, lines []string, s *Styles) string {
+	view := "\n\n" + s.Help.Render(file) + "\n\n"
+	env, err := utils.ParseEnvList(lines)
+	if err != nil {
+		return view + s.Help.Render(err.Error())
+	}
+	if len(env) == 0 {
+		return view + s.Help.Render("# no variables")
+	}
+	for _, v := range env {
+		view += s.StatusHeader.Render(v.Key) + "=" + s.Highlight.Render(v.Value) + "\n"
+	}
+	return strings.TrimSuffix(view, "\n")
+}
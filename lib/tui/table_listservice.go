@@ -1,21 +1,27 @@
 package tui
 
 import (
+	"envme/lib/types"
 	"github.com/charmbracelet/bubbles/table"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"strings"
 )
 
-func NewListService() ListServiceModel {
+func NewListService(services []*types.StackStatus) ListServiceModel {
 	columns := []table.Column{
 		{Title: "Name", Width: 20},
-		{Title: "Image", Width: 20},
-		{Title: "Expose", Width: 50},
+		{Title: "Kind", Width: 12},
+		{Title: "State", Width: 10},
+		{Title: "Image", Width: 25},
+		{Title: "Uptime", Width: 15},
+		{Title: "Ports", Width: 25},
+		{Title: "Expose", Width: 40},
 	}
 
-	rows := []table.Row{
-		{"api", "backend:latest", "8080:api.envme.bid"},
-		{"web", "frontend:latest", "3000:envme.bid"},
+	rows := make([]table.Row, 0, len(services))
+	for _, srv := range services {
+		rows = append(rows, table.Row{srv.Name, srv.Kind, srv.State, srv.Image, srv.Uptime, strings.Join(srv.Ports, ", "), strings.Join(srv.ExposeHostnames(), ", ")})
 	}
 
 	t := table.New(
@@ -57,8 +63,11 @@ func (m ListServiceModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case "q", "ctrl+c":
 			return m, tea.Quit
 		case "enter":
+			if len(m.table.SelectedRow()) == 0 {
+				return m, nil
+			}
 			return m, tea.Batch(
-				tea.Printf("Let's go to %s!", m.table.SelectedRow()[1]),
+				tea.Printf("Let's go to %s!", m.table.SelectedRow()[0]),
 			)
 		}
 	}
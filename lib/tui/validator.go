@@ -1,8 +1,11 @@
 package tui
 
 import (
+	"context"
+	"envme/pkg/envme"
 	"fmt"
 	"github.com/spf13/viper"
+	"strings"
 )
 
 func VRequiredAndSave(key string, msg string) func(value string) error {
@@ -24,3 +27,66 @@ func VSave(key string) func(value string) error {
 		return nil
 	}
 }
+
+func VExposeAndSave(key string) func(value string) error {
+	return func(value string) error {
+		lines := splitLines(value)
+		_, err := envme.ParseExposeRules(lines)
+		if err != nil {
+			return err
+		}
+
+		viper.Set(key, lines)
+
+		return nil
+	}
+}
+
+// VImageAndSave requires an image unless a catalog service is selected
+func VImageAndSave(key, from string) func(value string) error {
+	return func(value string) error {
+		if value == "" && viper.GetString(from) == "" {
+			return fmt.Errorf("Image name is required")
+		}
+
+		viper.Set(key, value)
+
+		return nil
+	}
+}
+
+// VLinesAndSave saves the non-empty lines of a text field as a list
+func VLinesAndSave(key string) func(value string) error {
+	return func(value string) error {
+		viper.Set(key, splitLines(value))
+
+		return nil
+	}
+}
+
+// VDoneAndValidate asks to finish the form, then checks the compose file it
+// creates against the compose schema, once on submit rather than on every key
+func VDoneAndValidate(stack string, preview func() ([]byte, error)) func(v bool) error {
+	return func(v bool) error {
+		if !v {
+			return fmt.Errorf("Welp, finish up then")
+		}
+
+		content, err := preview()
+		if err != nil {
+			return err
+		}
+		return envme.ValidateCompose(context.Background(), viper.GetString(stack), content)
+	}
+}
+
+// splitLines returns the non-empty lines of a text field
+func splitLines(value string) []string {
+	var lines []string
+	for _, line := range strings.Split(value, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -1,5 +1,10 @@
 package types
 
+import (
+	"fmt"
+	"github.com/mattn/go-shellwords"
+)
+
 type Compose struct {
 	Version  string              `yaml:"version,omitempty"`
 	Services map[string]*Service `yaml:"services"`
@@ -8,21 +13,141 @@ type Compose struct {
 }
 
 type Service struct {
-	ContainerName string    `yaml:"container_name,omitempty"`
-	Build         *Build    `yaml:"build,omitempty"`
-	Image         string    `yaml:"image,omitempty"`
-	Restart       string    `yaml:"restart,omitempty"`
-	Volumes       []string  `yaml:"volumes,omitempty"`
-	Environment   []string  `yaml:"environment,omitempty"`
-	Command       string    `yaml:"command,omitempty"`
-	Networks      *[]string `yaml:"networks,omitempty"`
-	ExtraHosts    *[]string `yaml:"extra_hosts,omitempty"`
+	ContainerName string            `yaml:"container_name,omitempty"`
+	Build         *Build            `yaml:"build,omitempty"`
+	Image         string            `yaml:"image,omitempty"`
+	Restart       string            `yaml:"restart,omitempty"`
+	Profiles      []string          `yaml:"profiles,omitempty"`
+	User          string            `yaml:"user,omitempty"`
+	WorkingDir    string            `yaml:"working_dir,omitempty"`
+	Entrypoint    ShellCommand      `yaml:"entrypoint,omitempty"`
+	Volumes       []string          `yaml:"volumes,omitempty"`
+	Environment   []string          `yaml:"environment,omitempty"`
+	EnvFile       []string          `yaml:"env_file,omitempty"`
+	Command       ShellCommand      `yaml:"command,omitempty"`
+	Ports         []string          `yaml:"ports,omitempty"`
+	Expose        []string          `yaml:"expose,omitempty"`
+	Labels        map[string]string `yaml:"labels,omitempty"`
+	DependsOn     DependsOn         `yaml:"depends_on,omitempty"`
+	Healthcheck   *Healthcheck      `yaml:"healthcheck,omitempty"`
+	Deploy        *Deploy           `yaml:"deploy,omitempty"`
+	Networks      *[]string         `yaml:"networks,omitempty"`
+	ExtraHosts    *[]string         `yaml:"extra_hosts,omitempty"`
+}
+
+// ShellCommand is the command or entrypoint of a service.
+// Like compose, it reads a string by splitting it as a shell would and always writes the list form.
+type ShellCommand []string
+
+func (c *ShellCommand) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var command string
+	if err := unmarshal(&command); err == nil {
+		args, err := shellwords.Parse(command)
+		if err != nil {
+			return fmt.Errorf("invalid command %q: %w", command, err)
+		}
+		*c = args
+		return nil
+	}
+
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*c = list
+	return nil
 }
 
 type Build struct {
-	Context    string `yaml:"context"`
-	Dockerfile string `yaml:"dockerfile,omitempty"`
-	Target     string `yaml:"target,omitempty"`
+	Context    string            `yaml:"context"`
+	Dockerfile string            `yaml:"dockerfile,omitempty"`
+	Target     string            `yaml:"target,omitempty"`
+	Args       map[string]string `yaml:"args,omitempty"`
+}
+
+// Conditions of a depends_on entry
+const (
+	ConditionStarted   = "service_started"
+	ConditionHealthy   = "service_healthy"
+	ConditionCompleted = "service_completed_successfully"
+)
+
+// DependsOn maps the services a service depends on to their condition.
+// It reads both the short (list) and the long (map) syntax and writes the long one.
+type DependsOn map[string]*Dependency
+
+type Dependency struct {
+	Condition string `yaml:"condition"`
+	Restart   bool   `yaml:"restart,omitempty"`
+	Required  *bool  `yaml:"required,omitempty"`
+}
+
+func (d *DependsOn) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var names []string
+	if err := unmarshal(&names); err == nil {
+		*d = make(DependsOn, len(names))
+		for _, name := range names {
+			(*d)[name] = &Dependency{Condition: ConditionStarted}
+		}
+		return nil
+	}
+
+	var long map[string]*Dependency
+	if err := unmarshal(&long); err != nil {
+		return err
+	}
+	for _, dependency := range long {
+		if dependency != nil && dependency.Condition == "" {
+			dependency.Condition = ConditionStarted
+		}
+	}
+	*d = long
+	return nil
+}
+
+type Healthcheck struct {
+	Test          HealthcheckTest `yaml:"test,omitempty"`
+	Interval      string          `yaml:"interval,omitempty"`
+	Timeout       string          `yaml:"timeout,omitempty"`
+	StartPeriod   string          `yaml:"start_period,omitempty"`
+	StartInterval string          `yaml:"start_interval,omitempty"`
+	Retries       *int            `yaml:"retries,omitempty"`
+	Disable       bool            `yaml:"disable,omitempty"`
+}
+
+// HealthcheckTest is the command testing the health of a service, starting with NONE, CMD or CMD-SHELL.
+// Like compose, it reads a string as a CMD-SHELL command and always writes the list form.
+type HealthcheckTest []string
+
+func (t *HealthcheckTest) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var command string
+	if err := unmarshal(&command); err == nil {
+		*t = HealthcheckTest{"CMD-SHELL", command}
+		return nil
+	}
+
+	var list []string
+	if err := unmarshal(&list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
+}
+
+type Deploy struct {
+	Resources *Resources `yaml:"resources,omitempty"`
+}
+
+type Resources struct {
+	Limits       *Resource `yaml:"limits,omitempty"`
+	Reservations *Resource `yaml:"reservations,omitempty"`
+}
+
+// Resource limits a service to a number of CPUs (e.g. "0.5"), an amount of memory (e.g. "512m") and of processes.
+type Resource struct {
+	Cpus   string `yaml:"cpus,omitempty"`
+	Memory string `yaml:"memory,omitempty"`
+	Pids   int64  `yaml:"pids,omitempty"`
 }
 
 type Network struct {
@@ -0,0 +1,163 @@
+package types
+
+import (
+	"context"
+	composeloader "github.com/compose-spec/compose-go/v2/loader"
+	composetypes "github.com/compose-spec/compose-go/v2/types"
+	"gopkg.in/yaml.v2"
+	"reflect"
+	"testing"
+)
+
+func loadCompose(t *testing.T, content []byte) *composetypes.Project {
+	t.Helper()
+	project, err := composeloader.LoadWithContext(context.Background(), composetypes.ConfigDetails{
+		WorkingDir:  t.TempDir(),
+		ConfigFiles: []composetypes.ConfigFile{{Filename: "docker-compose.yaml", Content: content}},
+	}, func(options *composeloader.Options) {
+		options.SetProjectName("test", true)
+		options.SkipInterpolation = true
+		options.SkipResolveEnvironment = true
+		options.ResolvePaths = false
+	})
+	if err != nil {
+		t.Fatalf("%v\n%s", err, content)
+	}
+	return project
+}
+
+func TestComposeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		service string
+	}{
+		{
+			name: "string forms",
+			service: `
+    command: uvicorn main:app --host 0.0.0.0 --reload
+    entrypoint: /docker-entrypoint.sh "with space"
+    healthcheck:
+      test: curl -f http://localhost:8000 || exit 1`,
+		},
+		{
+			name: "list forms",
+			service: `
+    command: ["server", "/data", "--console-address", ":9001"]
+    entrypoint: ["/bin/sh", "-c"]
+    healthcheck:
+      test: ["CMD-SHELL", "pg_isready -U $$POSTGRES_USER"]`,
+		},
+		{
+			name: "quoted string command",
+			service: `
+    command: sh -c 'echo "$$HOME" && sleep 1'`,
+		},
+		{
+			name: "disabled healthcheck",
+			service: `
+    healthcheck:
+      test: ["NONE"]`,
+		},
+		{
+			name: "ports",
+			service: `
+    ports:
+      - "8080:80"
+      - 127.0.0.1:5432:5432
+      - "[::1]:9000:9000/udp"
+      - 3000-3001:3000-3001
+      - "9229"`,
+		},
+		{
+			name: "expose",
+			service: `
+    expose:
+      - 3000
+      - "8000-8001/udp"`,
+		},
+		{
+			name: "short depends_on",
+			service: `
+    depends_on: [db]`,
+		},
+		{
+			name: "long depends_on",
+			service: `
+    depends_on:
+      db:
+        condition: service_healthy
+        restart: true
+        required: false`,
+		},
+		{
+			name: "labels",
+			service: `
+    labels:
+      traefik.enable: "true"
+      com.example.empty: ""`,
+		},
+		{
+			name: "deploy resources",
+			service: `
+    deploy:
+      resources:
+        limits:
+          cpus: "0.5"
+          memory: 512M
+          pids: 100
+        reservations:
+          memory: 128m`,
+		},
+		{
+			name: "profiles, user and working_dir",
+			service: `
+    profiles: [debug, tools]
+    user: "1000:1000"
+    working_dir: /app`,
+		},
+		{
+			name: "build args",
+			service: `
+    build:
+      context: .
+      dockerfile: Dockerfile
+      target: development
+      args:
+        NODE_ENV: development
+        EMPTY: ""`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// db is there for depends_on to be consistent
+			content := []byte("services:\n  db:\n    image: db\n  app:\n    image: app" + tt.service + "\n")
+
+			var config Compose
+			if err := yaml.Unmarshal(content, &config); err != nil {
+				t.Fatal(err)
+			}
+			written, err := yaml.Marshal(&config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The service is compared as compose reads it, whatever the syntax it was written with
+			want := loadCompose(t, content).Services["app"]
+			got := loadCompose(t, written).Services["app"]
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got:\n%+v\nwant:\n%+v\nwritten:\n%s", got, want, written)
+			}
+		})
+	}
+}
+
+func TestShellCommandErrors(t *testing.T) {
+	var service Service
+	if err := yaml.Unmarshal([]byte(`command: echo "unterminated`), &service); err == nil {
+		t.Errorf("command = %q, want an error", service.Command)
+	}
+	if err := yaml.Unmarshal([]byte("command: {a: b}"), &service); err == nil {
+		t.Error("a map command was accepted")
+	}
+}
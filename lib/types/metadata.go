@@ -0,0 +1,30 @@
+package types
+
+import "time"
+
+// MetadataVersion is the version of the envme.yaml document written by this release.
+const MetadataVersion = 1
+
+const (
+	KindService     = "service"
+	KindDevelopment = "development"
+	KindTunnel      = "tunnel"
+	KindProxy       = "proxy"
+)
+
+// Metadata is the envme.yaml document stored alongside the docker-compose.yaml
+// of every stack, holding what envme knows about it beyond the compose file.
+type Metadata struct {
+	Version      int            `yaml:"version" json:"version"`
+	Kind         string         `yaml:"kind" json:"kind"`
+	Image        string         `yaml:"image,omitempty" json:"image,omitempty"`
+	Dir          string         `yaml:"dir,omitempty" json:"dir,omitempty"`
+	Template     string         `yaml:"template,omitempty" json:"template,omitempty"`
+	Catalog      string         `yaml:"catalog,omitempty" json:"catalog,omitempty"`
+	EnvFiles     []string       `yaml:"env_files,omitempty" json:"env_files,omitempty"`
+	Expose       []*ExposeRule  `yaml:"expose,omitempty" json:"expose,omitempty"`
+	Publish      []*PublishRule `yaml:"publish,omitempty" json:"publish,omitempty"`
+	Local        []*ExposeRule  `yaml:"local,omitempty" json:"local,omitempty"`
+	CreatedAt    time.Time      `yaml:"created_at" json:"created_at"`
+	EnvmeVersion string         `yaml:"envme_version" json:"envme_version"`
+}
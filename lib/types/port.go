@@ -0,0 +1,52 @@
+package types
+
+import (
+	"net"
+	"strconv"
+	"strings"
+)
+
+// PublishRule publishes a container port of a stack service on a host port.
+type PublishRule struct {
+	// Service is empty for the main service of the stack
+	Service   string `yaml:"service,omitempty" json:"service,omitempty"`
+	HostIP    string `yaml:"host_ip,omitempty" json:"host_ip,omitempty"`
+	Host      int    `yaml:"host" json:"host"`
+	Container int    `yaml:"container" json:"container"`
+	Protocol  string `yaml:"protocol,omitempty" json:"protocol,omitempty"`
+	// Auto is set when envme picked the host port
+	Auto bool `yaml:"auto,omitempty" json:"auto,omitempty"`
+}
+
+// String returns the rule in the compose ports format [<host-ip>:]<host-port>:<container-port>[/<protocol>].
+func (r *PublishRule) String() string {
+	spec := strconv.Itoa(r.Host) + ":" + strconv.Itoa(r.Container)
+	if r.HostIP != "" {
+		spec = hostIP(r.HostIP) + ":" + spec
+	}
+	if r.Protocol != "" && r.Protocol != "tcp" {
+		spec += "/" + r.Protocol
+	}
+	return spec
+}
+
+// Network returns the network of the host port, tcp or udp.
+func (r *PublishRule) Network() string {
+	if r.Protocol == "" {
+		return "tcp"
+	}
+	return r.Protocol
+}
+
+// Address returns the host address the port is published on.
+func (r *PublishRule) Address() string {
+	return net.JoinHostPort(r.HostIP, strconv.Itoa(r.Host))
+}
+
+// hostIP brackets an IPv6 host ip, as in the compose ports format.
+func hostIP(ip string) string {
+	if strings.Contains(ip, ":") {
+		return "[" + ip + "]"
+	}
+	return ip
+}
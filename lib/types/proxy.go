@@ -0,0 +1,15 @@
+package types
+
+// ProxyTLSConfig is the dynamic configuration of the envme proxy listing the
+// certificates it serves, read by the traefik file provider.
+type ProxyTLSConfig struct {
+	TLS struct {
+		Certificates []*ProxyCertificate `yaml:"certificates"`
+	} `yaml:"tls"`
+}
+
+// ProxyCertificate is a certificate and its key, as paths in the proxy container.
+type ProxyCertificate struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+}
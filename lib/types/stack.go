@@ -0,0 +1,36 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+)
+
+const (
+	StateRunning = "running"
+	StateExited  = "exited"
+	StateMissing = "missing"
+	// StateUnknown is reported for stacks whose metadata cannot be read
+	StateUnknown = "unknown"
+)
+
+// StackStatus is the live state of an envme stack as reported by the Docker daemon.
+type StackStatus struct {
+	Name   string        `json:"name" yaml:"name"`
+	Kind   string        `json:"kind,omitempty" yaml:"kind,omitempty"`
+	State  string        `json:"state" yaml:"state"`
+	Image  string        `json:"image" yaml:"image"`
+	Uptime string        `json:"uptime,omitempty" yaml:"uptime,omitempty"`
+	Ports  []string      `json:"ports,omitempty" yaml:"ports,omitempty"`
+	Expose []*ExposeRule `json:"expose,omitempty" yaml:"expose,omitempty"`
+	Local  []*ExposeRule `json:"local,omitempty" yaml:"local,omitempty"`
+	Error  string        `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// ExposeHostnames returns the public and local hostnames of the stack in the <port>:<hostname> format.
+func (s *StackStatus) ExposeHostnames() []string {
+	var hostnames []string
+	for _, rule := range slices.Concat(s.Expose, s.Local) {
+		hostnames = append(hostnames, fmt.Sprintf("%d:%s", rule.Port, rule.Hostname))
+	}
+	return hostnames
+}
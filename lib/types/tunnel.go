@@ -0,0 +1,21 @@
+package types
+
+// TunnelConfig is the cloudflared config.yaml of the envme tunnel.
+type TunnelConfig struct {
+	Tunnel          string         `yaml:"tunnel"`
+	CredentialsFile string         `yaml:"credentials-file"`
+	Ingress         []*IngressRule `yaml:"ingress"`
+}
+
+// IngressRule routes a public hostname to a service on the envme network.
+// A rule without hostname is the catch-all and must be the last one.
+type IngressRule struct {
+	Hostname string `yaml:"hostname,omitempty"`
+	Service  string `yaml:"service"`
+}
+
+// ExposeRule publishes a port of a stack on a public hostname.
+type ExposeRule struct {
+	Port     int    `yaml:"port" json:"port"`
+	Hostname string `yaml:"hostname" json:"hostname"`
+}
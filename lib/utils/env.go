@@ -1,45 +1,407 @@
 package utils
 
-import "github.com/spf13/viper"
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
 
-func ReadDotEnv() error {
-	file := viper.GetString("env-file")
-	if file == "" {
-		return nil
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.\-]*$`)
+
+// EnvVar is a single environment variable.
+type EnvVar struct {
+	Key   string
+	Value string
+}
+
+// Env is an ordered list of environment variables. Setting an existing key
+// replaces its value in place.
+type Env []EnvVar
+
+func (e *Env) Set(key, value string) {
+	for i := range *e {
+		if (*e)[i].Key == key {
+			(*e)[i].Value = value
+			return
+		}
 	}
+	*e = append(*e, EnvVar{Key: key, Value: value})
+}
+
+func (e Env) Get(key string) (string, bool) {
+	for _, v := range e {
+		if v.Key == key {
+			return v.Value, true
+		}
+	}
+	return "", false
+}
+
+// Unset removes the key and reports whether it was set.
+func (e *Env) Unset(key string) bool {
+	for i, v := range *e {
+		if v.Key == key {
+			*e = append((*e)[:i], (*e)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Merge sets every variable of other, which takes precedence.
+func (e *Env) Merge(other Env) {
+	for _, v := range other {
+		e.Set(v.Key, v.Value)
+	}
+}
+
+// Map returns the variables as a map, as used for compose interpolation.
+func (e Env) Map() map[string]string {
+	m := make(map[string]string, len(e))
+	for _, v := range e {
+		m[v.Key] = v.Value
+	}
+	return m
+}
+
+// List returns the variables in the KEY=VALUE format.
+func (e Env) List() []string {
+	list := make([]string, 0, len(e))
+	for _, v := range e {
+		list = append(list, v.Key+"="+v.Value)
+	}
+	return list
+}
+
+// ParseEnvAssignment parses a single KEY=VALUE assignment as given to --env.
+// An optional export prefix and quotes around the value are removed, the value
+// is otherwise kept as is. A bare KEY takes its value from the current environment.
+func ParseEnvAssignment(s string) (EnvVar, bool, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "export ")
+	key, value, ok := strings.Cut(s, "=")
+	key = strings.TrimSpace(key)
+	if !envKeyPattern.MatchString(key) {
+		return EnvVar{}, false, fmt.Errorf("invalid environment variable %q", s)
+	}
+	if !ok {
+		value, ok = os.LookupEnv(key)
+		return EnvVar{Key: key, Value: value}, ok, nil
+	}
+
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		quote := value[0]
+		value = value[1 : len(value)-1]
+		if quote == '"' {
+			value = unescapeDoubleQuoted(value)
+		}
+	}
+	return EnvVar{Key: key, Value: value}, true, nil
+}
+
+// ParseEnvList parses a list of assignments, later ones taking precedence.
+func ParseEnvList(list []string) (Env, error) {
+	var env Env
+	for _, s := range list {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		v, ok, err := ParseEnvAssignment(s)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			env.Set(v.Key, v.Value)
+		}
+	}
+	return env, nil
+}
+
+// EnvError is a syntax error in an env file.
+type EnvError struct {
+	Line int
+	Msg  string
+}
+
+func (e *EnvError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// ParseEnv parses the content of an env file: KEY=VALUE lines with an optional
+// export prefix, # comments, and single or double quoted values that may span
+// several lines. Double quoted values support \n, \r, \t, \" and \\ escapes.
+// Values are kept as written, see ParseDotEnv for variable expansion.
+func ParseEnv(content string) (Env, error) {
+	return parseEnv(content, false, nil)
+}
+
+// ParseDotEnv parses the content of an env file like ParseEnv and expands
+// $VAR, ${VAR} and ${VAR:-default} in unquoted and double quoted values, from
+// the entries above, then from lookup. \$ is a literal $.
+func ParseDotEnv(content string, lookup func(string) (string, bool)) (Env, error) {
+	return parseEnv(content, true, lookup)
+}
+
+func parseEnv(content string, expand bool, lookup func(string) (string, bool)) (Env, error) {
+	var env Env
+	resolve := func(key string) (string, bool) {
+		if value, ok := env.Get(key); ok {
+			return value, true
+		}
+		if lookup != nil {
+			return lookup(key)
+		}
+		return "", false
+	}
+
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, rest, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok {
+			return nil, &EnvError{Line: lineNo, Msg: fmt.Sprintf("expected KEY=VALUE, got %q", line)}
+		}
+		if !envKeyPattern.MatchString(key) {
+			return nil, &EnvError{Line: lineNo, Msg: fmt.Sprintf("invalid key %q", key)}
+		}
 
-	// Read the file and set the environment variables
-	config := viper.New()
-	config.SetConfigFile(file)
-	err := config.ReadInConfig()
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" || (rest[0] != '"' && rest[0] != '\'') {
+			value := stripInlineComment(rest)
+			if expand {
+				value = expandEnv(value, resolve)
+			}
+			env.Set(key, value)
+			continue
+		}
+
+		// Quoted value, possibly continuing on the following lines
+		quote := rest[0]
+		value := rest[1:]
+		end := closingQuote(value, quote)
+		for end < 0 && i+1 < len(lines) {
+			i++
+			value += "\n" + lines[i]
+			end = closingQuote(value, quote)
+		}
+		if end < 0 {
+			return nil, &EnvError{Line: lineNo, Msg: fmt.Sprintf("unterminated quoted value for %s", key)}
+		}
+
+		after := strings.TrimSpace(value[end+1:])
+		if after != "" && !strings.HasPrefix(after, "#") {
+			return nil, &EnvError{Line: i + 1, Msg: fmt.Sprintf("unexpected %q after quoted value of %s", after, key)}
+		}
+		value = value[:end]
+		if quote == '"' {
+			value = unescapeDoubleQuoted(value)
+			if expand {
+				value = expandEnv(value, resolve)
+			}
+		}
+		env.Set(key, value)
+	}
+	return env, nil
+}
+
+// closingQuote returns the index of the quote ending the value, skipping
+// escaped double quotes, or -1.
+func closingQuote(value string, quote byte) int {
+	for i := 0; i < len(value); i++ {
+		switch {
+		case quote == '"' && value[i] == '\\':
+			i++
+		case value[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// stripInlineComment removes a # comment preceded by whitespace from an unquoted value.
+func stripInlineComment(value string) string {
+	for i := 1; i < len(value); i++ {
+		if value[i] == '#' && (value[i-1] == ' ' || value[i-1] == '\t') {
+			return strings.TrimSpace(value[:i])
+		}
+	}
+	return strings.TrimSpace(value)
+}
+
+func unescapeDoubleQuoted(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case '"', '\\':
+			b.WriteByte(value[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// expandEnv replaces $VAR, ${VAR} and ${VAR:-default} using lookup, unknown
+// variables expand to an empty string. \$ is kept as a literal $.
+func expandEnv(value string, lookup func(string) (string, bool)) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch {
+		case value[i] == '\\' && i+1 < len(value) && value[i+1] == '$':
+			b.WriteByte('$')
+			i++
+		case value[i] == '$' && i+1 < len(value) && value[i+1] == '{':
+			end := strings.IndexByte(value[i:], '}')
+			if end < 0 {
+				b.WriteString(value[i:])
+				return b.String()
+			}
+			name, fallback, hasDefault := strings.Cut(value[i+2:i+end], ":-")
+			v, ok := lookup(name)
+			if (!ok || v == "") && hasDefault {
+				v = fallback
+			}
+			b.WriteString(v)
+			i += end
+		case value[i] == '$':
+			j := i + 1
+			for j < len(value) && (value[j] == '_' || isAlnum(value[j])) {
+				j++
+			}
+			if j == i+1 {
+				b.WriteByte('$')
+				continue
+			}
+			v, _ := lookup(value[i+1 : j])
+			b.WriteString(v)
+			i = j - 1
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// EnvFile is the env file of the main service of a stack, next to its docker-compose.yaml.
+const EnvFile = ".env"
+
+// ServiceEnvFile returns the env file of a service of the stack,
+// <service>.env for the services added next to the main one.
+func ServiceEnvFile(stack, service string) string {
+	if service == "" || service == stack {
+		return EnvFile
+	}
+	return service + EnvFile
+}
+
+// FormatEnv returns the variables as env file content. Values are double quoted
+// and escaped so that both ParseDotEnv and the compose env_file loader read them back as is.
+func FormatEnv(env Env) []byte {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`)
+	var b strings.Builder
+	for _, v := range env {
+		b.WriteString(v.Key + `="` + replacer.Replace(v.Value) + "\"\n")
+	}
+	return []byte(b.String())
+}
+
+// ReadEnvFile reads an env file of the given stack, which is empty when missing.
+func ReadEnvFile(name, file string) (Env, error) {
+	content, err := ReadServiceFile(name, file)
+	if errors.Is(err, os.ErrNotExist) {
+		return Env{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	env, err := ParseDotEnv(string(content), nil)
+	var envErr *EnvError
+	if errors.As(err, &envErr) {
+		return nil, fmt.Errorf("%s/%s:%d: %s", name, file, envErr.Line, envErr.Msg)
+	}
+	return env, err
+}
+
+// WriteEnvFile writes an env file of the given stack, readable by the owner only.
+func WriteEnvFile(name, file string, env Env) error {
+	err := WriteServiceFile(name, file, FormatEnv(env), 0600)
 	if err != nil {
 		return err
 	}
 
-	// Set the viper env to []string
-	var configSlice []string
-	for _, key := range config.AllKeys() {
-		configSlice = append(configSlice, key+"="+config.GetString(key))
+	// WriteFile keeps the mode of an existing file
+	dir, err := GetServiceDir(name)
+	if err != nil {
+		return err
 	}
-	viper.Set("env", configSlice)
+	return os.Chmod(filepath.Join(dir, file), 0600)
+}
 
-	return nil
+// GetEnv returns the environment variables given with --env.
+func GetEnv() (Env, error) {
+	return ParseEnvList(viper.GetStringSlice("env"))
 }
 
-func ConvertEnvToMap() map[string]string {
-	env := viper.GetStringSlice("env")
-	envMap := make(map[string]string)
-	for _, e := range env {
-		envMap[e] = e
+// ReadDotEnv loads the --env-file files in order, later files overriding earlier
+// ones, and returns their variables overridden by the --env ones.
+// Variables of a file may reference the ones of the previous files and of the
+// current environment.
+func ReadDotEnv() (Env, error) {
+	var env Env
+	lookup := func(key string) (string, bool) {
+		if value, ok := env.Get(key); ok {
+			return value, true
+		}
+		return os.LookupEnv(key)
 	}
-	return envMap
-}
+	for _, file := range viper.GetStringSlice("env-file") {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
 
-func ConvertEnvToMapP() map[string]*string {
-	env := viper.GetStringSlice("env")
-	envMap := make(map[string]*string)
-	for _, e := range env {
-		envMap[e] = &e
+		parsed, err := ParseDotEnv(string(content), lookup)
+		var envErr *EnvError
+		if errors.As(err, &envErr) {
+			return nil, fmt.Errorf("%s:%d: %s", file, envErr.Line, envErr.Msg)
+		}
+		if err != nil {
+			return nil, err
+		}
+		env.Merge(parsed)
+	}
+
+	flags, err := GetEnv()
+	if err != nil {
+		return nil, err
 	}
-	return envMap
+	env.Merge(flags)
+	return env, nil
 }
@@ -0,0 +1,249 @@
+package utils
+
+import (
+	"errors"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Env
+	}{
+		{
+			name:    "plain values",
+			content: "A=1\nB = two\nC=",
+			want:    Env{{"A", "1"}, {"B", "two"}, {"C", ""}},
+		},
+		{
+			name:    "export prefix",
+			content: "export A=1\n  export B=2",
+			want:    Env{{"A", "1"}, {"B", "2"}},
+		},
+		{
+			name:    "comments",
+			content: "# comment\n\nA=1 # inline\nB=a#b\n  # indented",
+			want:    Env{{"A", "1"}, {"B", "a#b"}},
+		},
+		{
+			name:    "quotes",
+			content: `A="double # kept"` + "\n" + `B='single $HOME \n'` + "\n" + `C="a" # comment` + "\n" + `D='"quoted"'`,
+			want:    Env{{"A", "double # kept"}, {"B", `single $HOME \n`}, {"C", "a"}, {"D", `"quoted"`}},
+		},
+		{
+			name:    "escapes in double quotes",
+			content: `A="line\nnext\ttab \"q\" \\ \x"`,
+			want:    Env{{"A", "line\nnext\ttab \"q\" \\ \\x"}},
+		},
+		{
+			name:    "multi-line values",
+			content: "A=\"first\nsecond\"\nB='-----BEGIN-----\nabc\n-----END-----'\nC=3",
+			want:    Env{{"A", "first\nsecond"}, {"B", "-----BEGIN-----\nabc\n-----END-----"}, {"C", "3"}},
+		},
+		{
+			name:    "crlf line endings",
+			content: "A=1\r\nB=\"2\"\r\n",
+			want:    Env{{"A", "1"}, {"B", "2"}},
+		},
+		{
+			name:    "later keys replace earlier ones in place",
+			content: "A=1\nB=2\nA=3",
+			want:    Env{{"A", "3"}, {"B", "2"}},
+		},
+		{
+			name:    "no expansion",
+			content: "A=1\nB=${A}",
+			want:    Env{{"A", "1"}, {"B", "${A}"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseEnv(tt.content)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEnvErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+	}{
+		{name: "missing equal sign", content: "A=1\nB", line: 2},
+		{name: "invalid key", content: "A=1\n\n1A=2", line: 3},
+		{name: "unterminated quote", content: "A=1\nB=\"open\nstill open", line: 2},
+		{name: "text after quoted value", content: "A=1\nB=\"x\ny\" z", line: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseEnv(tt.content)
+			var envErr *EnvError
+			if !errors.As(err, &envErr) {
+				t.Fatalf("error = %v, want an EnvError", err)
+			}
+			if envErr.Line != tt.line {
+				t.Errorf("line = %d, want %d (%v)", envErr.Line, tt.line, err)
+			}
+		})
+	}
+}
+
+func TestParseDotEnv(t *testing.T) {
+	lookup := func(key string) (string, bool) {
+		switch key {
+		case "HOME":
+			return "/home/dev", true
+		case "EMPTY":
+			return "", true
+		}
+		return "", false
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    Env
+	}{
+		{
+			name:    "from previous entries",
+			content: "HOST=db\nURL=postgres://$HOST:5432/${HOST}_test",
+			want:    Env{{"HOST", "db"}, {"URL", "postgres://db:5432/db_test"}},
+		},
+		{
+			name:    "entries take precedence over lookup",
+			content: "HOME=/app\nDIR=$HOME/src",
+			want:    Env{{"HOME", "/app"}, {"DIR", "/app/src"}},
+		},
+		{
+			name:    "from lookup",
+			content: "DIR=${HOME}/src",
+			want:    Env{{"DIR", "/home/dev/src"}},
+		},
+		{
+			name:    "defaults",
+			content: "A=${MISSING:-fallback}\nB=${EMPTY:-fallback}\nC=${HOME:-fallback}",
+			want:    Env{{"A", "fallback"}, {"B", "fallback"}, {"C", "/home/dev"}},
+		},
+		{
+			name:    "unknown variables are empty",
+			content: "A=x${MISSING}y$MISSING",
+			want:    Env{{"A", "xy"}},
+		},
+		{
+			name:    "escaped and lone dollars",
+			content: `A=\$HOME` + "\n" + `B="cost $ 5"` + "\n" + `C=${unclosed`,
+			want:    Env{{"A", "$HOME"}, {"B", "cost $ 5"}, {"C", "${unclosed"}},
+		},
+		{
+			name:    "double quotes expand, single quotes do not",
+			content: `A="$HOME"` + "\n" + `B='$HOME'`,
+			want:    Env{{"A", "/home/dev"}, {"B", "$HOME"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDotEnv(tt.content, lookup)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDotEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEnvAssignment(t *testing.T) {
+	t.Setenv("ENVME_TEST_VAR", "from-env")
+
+	tests := []struct {
+		in      string
+		want    EnvVar
+		ok      bool
+		wantErr bool
+	}{
+		{in: "A=1", want: EnvVar{"A", "1"}, ok: true},
+		{in: "export A=1", want: EnvVar{"A", "1"}, ok: true},
+		{in: "A=a=b", want: EnvVar{"A", "a=b"}, ok: true},
+		{in: `A="x\ny"`, want: EnvVar{"A", "x\ny"}, ok: true},
+		{in: `A='x\ny'`, want: EnvVar{"A", `x\ny`}, ok: true},
+		{in: `A="unbalanced`, want: EnvVar{"A", `"unbalanced`}, ok: true},
+		{in: "A=$HOME", want: EnvVar{"A", "$HOME"}, ok: true},
+		{in: "A=", want: EnvVar{"A", ""}, ok: true},
+		{in: "ENVME_TEST_VAR", want: EnvVar{"ENVME_TEST_VAR", "from-env"}, ok: true},
+		{in: "ENVME_TEST_UNSET", want: EnvVar{"ENVME_TEST_UNSET", ""}, ok: false},
+		{in: "1A=1", wantErr: true},
+		{in: "=1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, ok, err := ParseEnvAssignment(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseEnvAssignment(%q) expected an error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseEnvAssignment(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseEnvAssignment(%q) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFormatEnvRoundTrip(t *testing.T) {
+	env := Env{{"A", "plain"}, {"B", `quote " and \ backslash`}, {"C", "multi\nline"}, {"D", "$NOT_EXPANDED"}, {"E", ""}}
+
+	got, err := ParseDotEnv(string(FormatEnv(env)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, env) {
+		t.Errorf("round trip = %q, want %q", got, env)
+	}
+}
+
+func TestReadDotEnv(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.env")
+	second := filepath.Join(dir, "second.env")
+	if err := os.WriteFile(first, []byte("A='\"quoted\"'\nB=\"x\\\\y\"\nC=from-first\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("C=from-second\nD=${C}-$B\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Set("env-file", []string{first, second})
+	viper.Set("env", []string{"E=flag", "C=from-flag"})
+	t.Cleanup(func() {
+		viper.Set("env-file", nil)
+		viper.Set("env", nil)
+	})
+
+	got, err := ReadDotEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Env{{"A", `"quoted"`}, {"B", `x\y`}, {"C", "from-flag"}, {"D", `from-second-x\y`}, {"E", "flag"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadDotEnv() = %q, want %q", got, want)
+	}
+}
@@ -5,9 +5,22 @@ import (
 	"github.com/mitchellh/go-homedir"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
+// stackNamePattern is the compose project name pattern, stack names are used as project names
+var stackNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ValidateStackName checks that the name is a valid compose project name,
+// which also keeps it from escaping the app directory.
+func ValidateStackName(name string) error {
+	if !stackNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid service name %q, it must start with a lowercase letter or digit and contain only lowercase letters, digits, dashes and underscores", name)
+	}
+	return nil
+}
+
 func GetAppDir() (string, error) {
 	home, err := homedir.Dir()
 	if err != nil {
@@ -22,13 +35,24 @@ func GetAppDir() (string, error) {
 	return dir, nil
 }
 
+// GetListServices returns the names of every stack under the app directory.
+// Only directories holding a docker-compose.yaml are considered stacks.
 func GetListServices() ([]string, error) {
 	appDir, err := GetAppDir()
 	if err != nil {
 		return nil, err
 	}
 
-	return filepath.Glob(filepath.Join(appDir, "*"))
+	files, err := filepath.Glob(filepath.Join(appDir, "*", "docker-compose.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, file := range files {
+		names = append(names, filepath.Base(filepath.Dir(file)))
+	}
+	return names, nil
 }
 
 func GetConfigFile() (string, error) {
@@ -45,6 +69,23 @@ func GetConfigFile() (string, error) {
 	return file, nil
 }
 
+// HasService reports whether a stack with the given name exists under the app directory.
+func HasService(name string) bool {
+	return HasServiceFile(name, "docker-compose.yaml")
+}
+
+// HasServiceFile reports whether the directory of the given stack holds the file,
+// without creating the directory.
+func HasServiceFile(name, file string) bool {
+	appDir, err := GetAppDir()
+	if err != nil {
+		return false
+	}
+
+	_, err = os.Stat(filepath.Join(appDir, name, file))
+	return err == nil
+}
+
 func GetServiceDir(name string) (string, error) {
 	appDir, err := GetAppDir()
 	if err != nil {
@@ -58,19 +99,61 @@ func GetServiceDir(name string) (string, error) {
 	return srvDir, nil
 }
 
+// RemoveServiceDir deletes the directory of the given stack and everything in it.
+func RemoveServiceDir(name string) error {
+	appDir, err := GetAppDir()
+	if err != nil {
+		return err
+	}
+	if err := ValidateStackName(name); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(filepath.Join(appDir, name))
+}
+
 func WriteComposeFile(name string, content []byte) error {
+	return WriteServiceFile(name, "docker-compose.yaml", content, 0644)
+}
+
+func ReadComposeFile(name string) ([]byte, error) {
+	return ReadServiceFile(name, "docker-compose.yaml")
+}
+
+// WriteServiceFile writes a file into the directory of the given stack.
+func WriteServiceFile(name, file string, content []byte, perm os.FileMode) error {
 	dir, err := GetServiceDir(name)
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(filepath.Join(dir, "docker-compose.yaml"), content, 0644)
+	return os.WriteFile(filepath.Join(dir, file), content, perm)
 }
 
-func WriteDockerfile(dir string, template string) error {
-	fmt.Printf("Writing Dockerfile template %s to %s", template, dir)
-	// TODO: Add template logic
-	return os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(template), 0644)
+// ReadServiceFile reads a file from the directory of the given stack.
+func ReadServiceFile(name, file string) ([]byte, error) {
+	dir, err := GetServiceDir(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(filepath.Join(dir, file))
+}
+
+// HasDockerfile reports whether the directory holds a Dockerfile.
+func HasDockerfile(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "Dockerfile"))
+	return err == nil
+}
+
+// WriteDockerfile writes the Dockerfile into the directory, refusing to replace an existing one.
+func WriteDockerfile(dir string, content []byte) error {
+	file := filepath.Join(dir, "Dockerfile")
+	if HasDockerfile(dir) {
+		return fmt.Errorf("%s already exists, remove it or select no template to use it", file)
+	}
+
+	return os.WriteFile(file, content, 0644)
 }
 
 func EnsureDir(dir string) error {
@@ -0,0 +1,115 @@
+package envme
+
+import (
+	"context"
+	"envme/lib/types"
+	"envme/lib/utils"
+	"fmt"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/spf13/viper"
+)
+
+// AddService appends a service running an image to an existing stack, with its
+// own env file, and starts it without touching the other services of the stack.
+func AddService(ctx context.Context, stack, service, image, network string, env utils.Env) error {
+	if !utils.HasService(stack) {
+		return fmt.Errorf("service %q not found", stack)
+	}
+	metadata, err := ReadMetadata(stack)
+	if err != nil {
+		return err
+	}
+	if IsManaged(stack, metadata) {
+		return fmt.Errorf("%s is managed by envme, services cannot be added to it", stack)
+	}
+
+	publish, err := ParsePublishRules(viper.GetStringSlice("publish"))
+	if err != nil {
+		fmt.Printf("Error parsing publish: %v\n", err)
+		return err
+	}
+	for _, rule := range publish {
+		rule.Service = service
+	}
+	err = ResolvePublish(ctx, stack, publish)
+	if err != nil {
+		return err
+	}
+
+	project, err := ReadCompose(ctx, stack)
+	if err != nil {
+		return err
+	}
+	err = AddSidecar(ctx, project, sidecarOptions(stack, service, image, network, publish))
+	if err != nil {
+		fmt.Printf("Error building config: %v\n", err)
+		return err
+	}
+	err = writeCompose(ctx, stack, project)
+	if err != nil {
+		return err
+	}
+	err = utils.WriteEnvFile(stack, utils.ServiceEnvFile(stack, service), env)
+	if err != nil {
+		fmt.Printf("Error writing env file: %v\n", err)
+		return err
+	}
+	err = SavePublishRules(stack, publish...)
+	if err != nil {
+		fmt.Printf("Error writing metadata: %v\n", err)
+		return err
+	}
+
+	err = EnsureNetwork(ctx, network)
+	if err != nil {
+		fmt.Printf("Error creating network: %v\n", err)
+		return err
+	}
+
+	compose, project, err := LoadStack(ctx, stack)
+	if err != nil {
+		fmt.Printf("Error creating compose: %v\n", err)
+		return err
+	}
+	err = resolveSecrets(project)
+	if err != nil {
+		fmt.Printf("Error resolving secrets: %v\n", err)
+		return err
+	}
+
+	fmt.Printf("Adding %s to %s\n", service, stack)
+	services := []string{service}
+	return compose.Up(ctx, project, api.UpOptions{
+		Create: api.CreateOptions{Services: services, Recreate: api.RecreateDiverged, RecreateDependencies: api.RecreateDiverged},
+		Start:  api.StartOptions{Project: project, Services: services},
+	})
+}
+
+func sidecarOptions(stack, service, image, network string, publish []*types.PublishRule) SidecarOptions {
+	return SidecarOptions{
+		Stack:   stack,
+		Name:    service,
+		Image:   image,
+		Network: network,
+		Volumes: viper.GetStringSlice("volume"),
+		Ports:   publishSpecs(publish),
+	}
+}
+
+// PreviewSidecar returns the docker-compose.yaml AddService writes for the flags and the publish specs.
+// Host ports left to envme are only assigned when the service is added, the file is not validated.
+func PreviewSidecar(ctx context.Context, stack, service, image, network string, publish []string) ([]byte, error) {
+	rules, err := ParsePublishRules(publish)
+	if err != nil {
+		return nil, err
+	}
+	project, err := ReadCompose(ctx, stack)
+	if err != nil {
+		return nil, err
+	}
+	err = AddSidecar(ctx, project, sidecarOptions(stack, service, image, network, rules))
+	if err != nil {
+		return nil, err
+	}
+	return MarshalCompose(project)
+}
@@ -0,0 +1,139 @@
+package envme
+
+import (
+	"context"
+	"envme/lib/utils"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const handWritten = `# stack written by hand
+x-defaults: &defaults
+  restart: unless-stopped
+
+services:
+  app:
+    <<: *defaults
+    image: app
+    cap_add: [NET_ADMIN]
+    tmpfs:
+    - /run
+    networks:
+    - envme
+  # db: disabled for now
+
+  worker:
+    image: worker   # no env file
+    environment:
+      - TOKEN=abc$$def
+      - DEBUG
+    security_opt:
+    - no-new-privileges:true
+
+# the network
+networks:
+  envme:
+    external: true
+`
+
+func TestAddSidecar(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "comments and anchors", content: handWritten},
+		{name: "crlf", content: strings.ReplaceAll(handWritten, "\n", "\r\n")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setHome(t)
+			ctx := context.Background()
+			if err := utils.WriteComposeFile("app", []byte(tt.content)); err != nil {
+				t.Fatal(err)
+			}
+			before, err := ReadCompose(ctx, "app")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			project, err := ReadCompose(ctx, "app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = AddSidecar(ctx, project, SidecarOptions{
+				Stack:   "app",
+				Name:    "cache",
+				Image:   "redis",
+				Network: "envme",
+				Volumes: []string{"cache-data:/data"},
+				Ports:   []string{"6379:6379"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := writeCompose(ctx, "app", project); err != nil {
+				t.Fatal(err)
+			}
+
+			after, err := ReadCompose(ctx, "app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range []string{"app", "worker"} {
+				if !reflect.DeepEqual(after.Services[name], before.Services[name]) {
+					t.Errorf("%s = %+v, want %+v", name, after.Services[name], before.Services[name])
+				}
+			}
+			app := after.Services["app"]
+			if !reflect.DeepEqual(app.CapAdd, []string{"NET_ADMIN"}) || strings.Join(app.Tmpfs, ",") != "/run" || app.Restart != "unless-stopped" {
+				t.Errorf("app = %+v", app)
+			}
+			if _, ok := after.Extensions["x-defaults"]; !ok {
+				t.Error("the x-defaults extension was dropped")
+			}
+			if !reflect.DeepEqual(after.Networks, before.Networks) {
+				t.Errorf("networks = %+v, want %+v", after.Networks, before.Networks)
+			}
+			if _, ok := after.Volumes["cache-data"]; !ok {
+				t.Errorf("volume of the sidecar not declared: %+v", after.Volumes)
+			}
+			cache := after.Services["cache"]
+			if cache.Image != "redis" || len(cache.Ports) != 1 || cache.ContainerName != "app-cache" {
+				t.Errorf("cache = %+v", cache)
+			}
+			if len(cache.EnvFiles) != 1 || cache.EnvFiles[0].Path != utils.ServiceEnvFile("app", "cache") {
+				t.Errorf("env files of cache = %+v", cache.EnvFiles)
+			}
+
+			err = AddSidecar(ctx, after, SidecarOptions{Stack: "app", Name: "cache", Image: "redis", Network: "envme"})
+			if err == nil {
+				t.Error("a service was added twice")
+			}
+		})
+	}
+}
+
+func TestAddSidecarDeclaresNetwork(t *testing.T) {
+	setHome(t)
+	ctx := context.Background()
+
+	project, err := ParseCompose(ctx, "app", []byte("services:\n  app:\n    image: app\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := AddSidecar(ctx, project, SidecarOptions{Stack: "app", Name: "cache", Image: "redis", Network: "envme"}); err != nil {
+		t.Fatal(err)
+	}
+	if network, ok := project.Networks["envme"]; !ok || !bool(network.External) {
+		t.Errorf("networks = %+v, want the external envme network", project.Networks)
+	}
+	content, err := MarshalCompose(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateCompose(ctx, "app", content); err != nil {
+		t.Errorf("%v\n%s", err, content)
+	}
+}
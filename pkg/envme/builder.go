@@ -0,0 +1,218 @@
+package envme
+
+import (
+	"context"
+	"envme/lib/catalog"
+	"envme/lib/docker"
+	"envme/lib/templates"
+	"envme/lib/types"
+	"envme/lib/utils"
+	"fmt"
+	composetypes "github.com/compose-spec/compose-go/v2/types"
+	"gopkg.in/yaml.v2"
+	"path"
+)
+
+// ServiceOptions are the inputs of the compose file of a service.
+type ServiceOptions struct {
+	Name    string
+	Image   string
+	Network string
+	Volumes []string
+	// Ports are published on the host, in the compose ports format
+	Ports []string
+	// Catalog, when set, provides the default image and volumes and contributes its compose fragment
+	Catalog *catalog.Entry
+}
+
+// DevelopmentOptions are the inputs of the compose file of a development environment.
+type DevelopmentOptions struct {
+	Name      string
+	Dir       string
+	Network   string
+	MountPath string
+	Volumes   []string
+	Ports     []string
+	// Template, when set, contributes its compose fragment
+	Template *templates.Template
+}
+
+// BuildService returns the compose project of a service running an image.
+func BuildService(ctx context.Context, o ServiceOptions) (*composetypes.Project, error) {
+	image, specs := o.Image, o.Volumes
+	if o.Catalog != nil {
+		if image == "" {
+			image = o.Catalog.Image
+		}
+		specs = append(append([]string{}, o.Catalog.Volumes...), o.Volumes...)
+	}
+	volumes, named, err := ParseVolumes(specs)
+	if err != nil {
+		return nil, err
+	}
+
+	service := &types.Service{
+		ContainerName: o.Name,
+		Image:         image,
+		Restart:       "unless-stopped",
+		Volumes:       volumes,
+		EnvFile:       []string{utils.EnvFile},
+		Ports:         o.Ports,
+		Networks:      &[]string{o.Network},
+		ExtraHosts:    &[]string{"host.docker.internal:host-gateway"},
+	}
+	if o.Catalog != nil {
+		mergeService(service, o.Catalog.Compose)
+	}
+
+	return toProject(ctx, o.Name, newCompose(o.Name, o.Network, service, named))
+}
+
+// BuildDevelopment returns the compose project of a development environment built
+// from the development target of the Dockerfile in its directory.
+func BuildDevelopment(ctx context.Context, o DevelopmentOptions) (*composetypes.Project, error) {
+	if !path.IsAbs(o.MountPath) {
+		return nil, fmt.Errorf("invalid mount path %q, it must be an absolute path", o.MountPath)
+	}
+	volumes, named, err := ParseVolumes(o.Volumes)
+	if err != nil {
+		return nil, err
+	}
+
+	service := &types.Service{
+		ContainerName: o.Name,
+		Build: &types.Build{
+			Context:    o.Dir,
+			Dockerfile: "Dockerfile",
+			Target:     "development",
+		},
+		Restart:    "unless-stopped",
+		Volumes:    append(DevVolumes(o.Dir, o.MountPath), volumes...),
+		EnvFile:    []string{utils.EnvFile},
+		Ports:      o.Ports,
+		Networks:   &[]string{o.Network},
+		ExtraHosts: &[]string{"host.docker.internal:host-gateway"},
+	}
+	if o.Template != nil {
+		mergeService(service, o.Template.Compose)
+	}
+
+	return toProject(ctx, o.Name, newCompose(o.Name, o.Network, service, named))
+}
+
+// SidecarOptions are the inputs of a service added to an existing stack.
+type SidecarOptions struct {
+	Stack   string
+	Name    string
+	Image   string
+	Network string
+	Volumes []string
+	// Ports are published as is, in the compose format [<host-ip>:][<host-port>:]<container-port>[/<protocol>]
+	Ports []string
+}
+
+// AddSidecar adds a service running an image to the compose project of a stack,
+// with the volumes and networks it uses unless the project declares them already.
+func AddSidecar(ctx context.Context, project *composetypes.Project, o SidecarOptions) error {
+	if _, ok := project.Services[o.Name]; ok {
+		return fmt.Errorf("service %q already exists in %s", o.Name, o.Stack)
+	}
+	volumes, named, err := ParseVolumes(o.Volumes)
+	if err != nil {
+		return err
+	}
+
+	sidecar, err := toProject(ctx, o.Stack, newCompose(o.Name, o.Network, &types.Service{
+		ContainerName: o.Stack + "-" + o.Name,
+		Image:         o.Image,
+		Restart:       "unless-stopped",
+		Volumes:       volumes,
+		EnvFile:       []string{utils.ServiceEnvFile(o.Stack, o.Name)},
+		Ports:         o.Ports,
+		Networks:      &[]string{o.Network},
+		ExtraHosts:    &[]string{"host.docker.internal:host-gateway"},
+	}, named))
+	if err != nil {
+		return err
+	}
+
+	if project.Services == nil {
+		project.Services = composetypes.Services{}
+	}
+	project.Services[o.Name] = sidecar.Services[o.Name]
+	for name, volume := range sidecar.Volumes {
+		if project.Volumes == nil {
+			project.Volumes = composetypes.Volumes{}
+		}
+		if _, ok := project.Volumes[name]; !ok {
+			project.Volumes[name] = volume
+		}
+	}
+	for name, network := range sidecar.Networks {
+		if project.Networks == nil {
+			project.Networks = composetypes.Networks{}
+		}
+		if _, ok := project.Networks[name]; !ok {
+			project.Networks[name] = network
+		}
+	}
+	return nil
+}
+
+func newCompose(name, network string, service *types.Service, named []string) *types.Compose {
+	return &types.Compose{
+		Services: map[string]*types.Service{
+			name: service,
+		},
+		Networks: map[string]*types.Network{
+			network: {
+				External: true,
+			},
+		},
+		Volumes: namedVolumes(named),
+	}
+}
+
+// namedVolumes declares the named volumes used by the service with the default driver.
+func namedVolumes(names []string) map[string]*types.Volume {
+	if len(names) == 0 {
+		return nil
+	}
+	volumes := make(map[string]*types.Volume, len(names))
+	for _, name := range names {
+		volumes[name] = nil
+	}
+	return volumes
+}
+
+// toProject converts the compose file to the project compose reads from it.
+func toProject(ctx context.Context, name string, config *types.Compose) (*composetypes.Project, error) {
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCompose(ctx, name, content)
+}
+
+// ParseCompose reads the docker-compose.yaml content of a stack as written.
+func ParseCompose(ctx context.Context, name string, content []byte) (*composetypes.Project, error) {
+	project, err := docker.ParseCompose(ctx, name, content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid compose file of %s: %w", name, err)
+	}
+	return project, nil
+}
+
+// ValidateCompose checks the docker-compose.yaml content against the compose
+// schema and consistency rules before it is written.
+func ValidateCompose(ctx context.Context, name string, content []byte) error {
+	if _, err := docker.ValidateCompose(ctx, name, content); err != nil {
+		return fmt.Errorf("invalid compose file: %w", err)
+	}
+	return nil
+}
+
+// MarshalCompose returns the docker-compose.yaml content of the project.
+func MarshalCompose(project *composetypes.Project) ([]byte, error) {
+	return project.MarshalYAML()
+}
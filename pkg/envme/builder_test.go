@@ -0,0 +1,170 @@
+package envme
+
+import (
+	"bytes"
+	"context"
+	"envme/lib/catalog"
+	"envme/lib/templates"
+	"envme/lib/types"
+	"envme/lib/utils"
+	"github.com/mitchellh/go-homedir"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setHome points the envme directory to a temporary one.
+func setHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	homedir.Reset()
+	t.Cleanup(homedir.Reset)
+	return home
+}
+
+func TestBuildServiceRoundTrip(t *testing.T) {
+	setHome(t)
+	ctx := context.Background()
+
+	entry, err := catalog.Find("postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	project, err := BuildService(ctx, ServiceOptions{
+		Name:    "db",
+		Network: "envme",
+		Volumes: []string{"./init:/docker-entrypoint-initdb.d:ro"},
+		Ports:   []string{"5432:5432", "[::1]:5433:5432"},
+		Catalog: entry,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := MarshalCompose(project)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateCompose(ctx, "db", content); err != nil {
+		t.Fatalf("%v\n%s", err, content)
+	}
+	// Escaped dollars are kept for compose to pass them to the container
+	if !bytes.Contains(content, []byte("$$POSTGRES_USER")) {
+		t.Errorf("healthcheck lost its escaped variables:\n%s", content)
+	}
+
+	read, err := ParseCompose(ctx, "db", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := MarshalCompose(read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, content) {
+		t.Errorf("read and written again:\n%s\nwant:\n%s", again, content)
+	}
+}
+
+func TestWriteComposeRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		template *types.Service
+	}{
+		{
+			name:     "depends on a missing service",
+			template: &types.Service{DependsOn: types.DependsOn{"cache": {Condition: types.ConditionHealthy}}},
+		},
+		{
+			name:     "invalid duration",
+			template: &types.Service{Healthcheck: &types.Healthcheck{Test: types.HealthcheckTest{"NONE"}, Interval: "soon"}},
+		},
+		{
+			name:     "invalid volume",
+			template: &types.Service{Volumes: []string{"data:relative:rw"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setHome(t)
+			ctx := context.Background()
+
+			project, err := BuildDevelopment(ctx, DevelopmentOptions{
+				Name:      "web",
+				Dir:       t.TempDir(),
+				Network:   "envme",
+				MountPath: "/app",
+				Template:  &templates.Template{Name: "test", Compose: tt.template},
+			})
+			if err == nil {
+				err = writeCompose(ctx, "web", project)
+			}
+			if err == nil {
+				t.Fatal("invalid compose file accepted")
+			}
+
+			dir, err := utils.GetServiceDir("web")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "docker-compose.yaml")); !os.IsNotExist(err) {
+				t.Errorf("docker-compose.yaml written: %v", err)
+			}
+		})
+	}
+}
+
+func TestWriteComposeKeepsUnmodeledKeys(t *testing.T) {
+	setHome(t)
+	ctx := context.Background()
+
+	content := []byte(strings.Join([]string{
+		"name: app",
+		"services:",
+		"  app:",
+		"    cap_add:",
+		"      - NET_ADMIN",
+		"    image: app",
+		"    tmpfs:",
+		"      - /run",
+		"x-custom: value",
+		"",
+	}, "\n"))
+	project, err := ParseCompose(ctx, "app", content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeCompose(ctx, "app", project); err != nil {
+		t.Fatal(err)
+	}
+	written, err := utils.ReadComposeFile("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(written, content) {
+		t.Errorf("written:\n%s\nwant:\n%s", written, content)
+	}
+}
+
+func TestValidateComposeInterpolatesStackEnv(t *testing.T) {
+	setHome(t)
+	ctx := context.Background()
+	t.Setenv("IMAGE", "from-the-shell")
+	content := []byte("services:\n  app:\n    image: ${IMAGE:?required}\n")
+
+	if err := ValidateCompose(ctx, "app", content); err == nil {
+		t.Error("validated without the .env of the stack")
+	}
+	if utils.HasServiceFile("app", "") {
+		t.Error("validating created the directory of the stack")
+	}
+
+	if err := utils.WriteEnvFile("app", utils.EnvFile, utils.Env{{Key: "IMAGE", Value: "app"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateCompose(ctx, "app", content); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,144 @@
+package envme
+
+import (
+	"envme/lib/certs"
+	"envme/lib/types"
+	"envme/lib/utils"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	caDir = "ca"
+	// proxyCertsDir holds the certificates of the local hostnames in the proxy
+	// stack, it is mounted at proxyCertsPath and watched by the proxy
+	proxyCertsDir  = "certs"
+	proxyCertsPath = "/etc/traefik/certs"
+	proxyTLSConfig = "tls.yaml"
+)
+
+// LoadCA loads the local CA under ~/.envme/ca, generating it on first use.
+func LoadCA() (*certs.CA, error) {
+	dir, err := utils.GetAppDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return certs.LoadOrCreateCA(filepath.Join(dir, caDir))
+}
+
+// CAPath returns the path of the root certificate of the local CA.
+func CAPath() (string, error) {
+	if _, err := LoadCA(); err != nil {
+		return "", err
+	}
+
+	dir, err := utils.GetAppDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, caDir, certs.CertFile), nil
+}
+
+// ExportCA writes the PEM encoded root certificate of the local CA.
+func ExportCA(w io.Writer) error {
+	ca, err := LoadCA()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(ca.PEM)
+	return err
+}
+
+// localHostnames returns the hostnames exposed through the proxy by every stack.
+func localHostnames() ([]string, error) {
+	stacks, err := utils.GetListServices()
+	if err != nil {
+		return nil, err
+	}
+
+	var hostnames []string
+	for _, stack := range stacks {
+		metadata, err := ReadMetadata(stack)
+		if err != nil {
+			continue
+		}
+		for _, rule := range metadata.Local {
+			hostnames = append(hostnames, rule.Hostname)
+		}
+	}
+	return hostnames, nil
+}
+
+// issueLocalCerts issues the certificates of the hostnames that are missing,
+// expiring or signed by another CA into the proxy stack, and writes the proxy
+// TLS config listing every certificate there. It returns the certificates directory.
+func issueLocalCerts(hostnames []string) (string, error) {
+	ca, err := LoadCA()
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := utils.GetServiceDir(ProxyStack)
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, proxyCertsDir)
+	if err := utils.EnsureDir(dir); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	for _, hostname := range hostnames {
+		certFile, keyFile := certFiles(dir, hostname)
+		if content, err := os.ReadFile(certFile); err == nil && ca.Valid(content, hostname, now) {
+			continue
+		}
+
+		certPEM, keyPEM, err := ca.Issue(hostname, now)
+		if err != nil {
+			return "", fmt.Errorf("error issuing certificate for %s: %w", hostname, err)
+		}
+		if err := os.WriteFile(keyFile, keyPEM, 0600); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(certFile, certPEM, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	return dir, writeProxyTLSConfig(dir)
+}
+
+func certFiles(dir, hostname string) (string, string) {
+	return filepath.Join(dir, hostname+".crt"), filepath.Join(dir, hostname+".key")
+}
+
+// writeProxyTLSConfig lists the certificates of the directory in its tls.yaml.
+func writeProxyTLSConfig(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.crt"))
+	if err != nil {
+		return err
+	}
+
+	config := &types.ProxyTLSConfig{}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".crt")
+		config.TLS.Certificates = append(config.TLS.Certificates, &types.ProxyCertificate{
+			CertFile: proxyCertsPath + "/" + name + ".crt",
+			KeyFile:  proxyCertsPath + "/" + name + ".key",
+		})
+	}
+
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, proxyTLSConfig), content, 0644)
+}
@@ -0,0 +1,47 @@
+package envme
+
+import (
+	"envme/lib/catalog"
+	"envme/lib/utils"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// generatedLength is the length of the passwords generated for catalog services.
+const generatedLength = 24
+
+// WriteCatalog prints the catalog entries as a table.
+func WriteCatalog(w io.Writer, list []*catalog.Entry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tTITLE\tIMAGE\tPORT\tDESCRIPTION")
+	for _, e := range list {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", e.Name, e.Title, e.Image, e.Port, e.Description)
+	}
+	return tw.Flush()
+}
+
+// CatalogEnv returns the default variables of the entry overridden by env, with
+// a random password for every generated variable env does not set. It also
+// returns the names of the generated variables.
+func CatalogEnv(entry *catalog.Entry, env utils.Env) (utils.Env, []string, error) {
+	defaults, err := utils.ParseEnvList(entry.Env)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid env of catalog service %s: %w", entry.Name, err)
+	}
+	defaults.Merge(env)
+
+	var generated []string
+	for _, key := range entry.Generate {
+		if _, ok := defaults.Get(key); ok {
+			continue
+		}
+		password, err := catalog.GeneratePassword(generatedLength)
+		if err != nil {
+			return nil, nil, err
+		}
+		defaults.Set(key, password)
+		generated = append(generated, key)
+	}
+	return defaults, generated, nil
+}
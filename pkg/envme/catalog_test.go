@@ -0,0 +1,112 @@
+package envme
+
+import (
+	"context"
+	"envme/lib/catalog"
+	"envme/lib/utils"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestCatalogEntries(t *testing.T) {
+	setHome(t)
+	ctx := context.Background()
+
+	list, err := catalog.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) == 0 {
+		t.Fatal("the catalog is empty")
+	}
+
+	for _, entry := range list {
+		t.Run(entry.Name, func(t *testing.T) {
+			if entry.Title == "" || entry.Image == "" {
+				t.Errorf("entry = %+v, want a title and an image", entry)
+			}
+			if found, err := catalog.Find(entry.Title); err != nil || found.Name != entry.Name {
+				t.Errorf("Find(%q) = %v, %v", entry.Title, found, err)
+			}
+			if _, _, err := CatalogEnv(entry, nil); err != nil {
+				t.Fatal(err)
+			}
+			publish, err := fragmentPublish(nil, entry.Compose)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			project, err := BuildService(ctx, ServiceOptions{
+				Name:    entry.Name,
+				Network: "envme",
+				Ports:   publishSpecs(publish),
+				Catalog: entry,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if image := project.Services[entry.Name].Image; image != entry.Image {
+				t.Errorf("image = %q, want %q", image, entry.Image)
+			}
+			content, err := MarshalCompose(project)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := ValidateCompose(ctx, entry.Name, content); err != nil {
+				t.Errorf("%v\n%s", err, content)
+			}
+		})
+	}
+}
+
+func TestCatalogEnv(t *testing.T) {
+	entry := &catalog.Entry{
+		Name:     "db",
+		Env:      []string{"DB_USER=app", "DB_NAME=app"},
+		Generate: []string{"DB_PASSWORD", "DB_ROOT_PASSWORD"},
+	}
+	password := regexp.MustCompile(`^[a-zA-Z0-9]{24}$`)
+
+	env, generated, err := CatalogEnv(entry, utils.Env{{Key: "DB_NAME", Value: "shop"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"DB_PASSWORD", "DB_ROOT_PASSWORD"}; !reflect.DeepEqual(generated, want) {
+		t.Errorf("generated = %q, want %q", generated, want)
+	}
+	for key, want := range map[string]string{"DB_USER": "app", "DB_NAME": "shop"} {
+		if value, _ := env.Get(key); value != want {
+			t.Errorf("%s = %q, want %q", key, value, want)
+		}
+	}
+	first, _ := env.Get("DB_PASSWORD")
+	root, _ := env.Get("DB_ROOT_PASSWORD")
+	for _, value := range []string{first, root} {
+		if !password.MatchString(value) {
+			t.Errorf("generated password %q, want 24 letters and digits", value)
+		}
+	}
+	if first == root {
+		t.Error("the same password was generated twice")
+	}
+
+	// A password given with --env is not generated
+	env, generated, err = CatalogEnv(entry, utils.Env{{Key: "DB_PASSWORD", Value: "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"DB_ROOT_PASSWORD"}; !reflect.DeepEqual(generated, want) {
+		t.Errorf("generated = %q, want %q", generated, want)
+	}
+	if value, _ := env.Get("DB_PASSWORD"); value != "secret" {
+		t.Errorf("DB_PASSWORD = %q, want the given one", value)
+	}
+	if again, _ := env.Get("DB_ROOT_PASSWORD"); again == root {
+		t.Error("the same password was generated for another stack")
+	}
+
+	if _, _, err := CatalogEnv(&catalog.Entry{Name: "bad", Env: []string{"=value"}}, nil); err == nil {
+		t.Error("an invalid default env was accepted")
+	}
+}
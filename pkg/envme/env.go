@@ -0,0 +1,173 @@
+package envme
+
+import (
+	"context"
+	"envme/lib/docker"
+	"envme/lib/types"
+	"envme/lib/utils"
+	"fmt"
+	composetypes "github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/compose/v2/pkg/api"
+	"slices"
+	"strings"
+)
+
+// ReadEnv returns the variables of the env file of a service of the given stack,
+// the main service when service is empty.
+func ReadEnv(name, service string) (utils.Env, error) {
+	if !utils.HasService(name) {
+		return nil, fmt.Errorf("service %q not found", name)
+	}
+
+	return utils.ReadEnvFile(name, utils.ServiceEnvFile(name, service))
+}
+
+// SetEnv sets the KEY=VALUE assignments in the env file of a service of the
+// given stack and recreates its container.
+func SetEnv(ctx context.Context, name, service string, assignments []string) error {
+	vars, err := utils.ParseEnvList(assignments)
+	if err != nil {
+		return err
+	}
+
+	env, err := ReadEnv(name, service)
+	if err != nil {
+		return err
+	}
+	env.Merge(vars)
+
+	return applyEnv(ctx, name, service, env)
+}
+
+// UnsetEnv removes the keys from the env file of a service of the given stack
+// and recreates its container.
+func UnsetEnv(ctx context.Context, name, service string, keys []string) error {
+	env, err := ReadEnv(name, service)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if !env.Unset(key) {
+			return fmt.Errorf("%s is not set on %s", key, name)
+		}
+	}
+
+	return applyEnv(ctx, name, service, env)
+}
+
+// applyEnv writes the env file of the service and recreates its container when
+// its config changed. Stopped stacks are recreated without being started.
+func applyEnv(ctx context.Context, name, service string, env utils.Env) error {
+	metadata, err := ReadMetadata(name)
+	if err != nil {
+		return err
+	}
+	if IsManaged(name, metadata) {
+		return fmt.Errorf("%s is managed by envme and has no env file", name)
+	}
+	if service == "" {
+		service = name
+	}
+
+	err = migrateEnv(ctx, name, service, &env)
+	if err != nil {
+		return err
+	}
+
+	err = utils.WriteEnvFile(name, utils.ServiceEnvFile(name, service), env)
+	if err != nil {
+		fmt.Printf("Error writing env file: %v\n", err)
+		return err
+	}
+
+	return recreate(ctx, name, api.RecreateDiverged, service)
+}
+
+// recreate recreates the containers of the services according to the policy,
+// and starts them when the stack is running.
+func recreate(ctx context.Context, name, policy string, services ...string) error {
+	compose, project, err := LoadStack(ctx, name)
+	if err != nil {
+		return err
+	}
+	err = resolveSecrets(project)
+	if err != nil {
+		return err
+	}
+
+	cli, err := docker.NewClient()
+	if err != nil {
+		return err
+	}
+	containers, err := docker.ListContainers(ctx, cli, name)
+	if err != nil {
+		return err
+	}
+
+	create := api.CreateOptions{Services: services, Recreate: policy, RecreateDependencies: api.RecreateDiverged}
+	for _, c := range containers {
+		if c.State == types.StateRunning {
+			return compose.Up(ctx, project, api.UpOptions{
+				Create: create,
+				Start:  api.StartOptions{Project: project, Services: services},
+			})
+		}
+	}
+	return compose.Create(ctx, project, create)
+}
+
+// migrateEnv moves the inline environment of services created before their env
+// file into env, keeping the variables already set there, and references the
+// env file from the service.
+func migrateEnv(ctx context.Context, name, service string, env *utils.Env) error {
+	project, err := ReadCompose(ctx, name)
+	if err != nil {
+		return err
+	}
+	s, ok := project.Services[service]
+	if !ok {
+		return fmt.Errorf("service %q not found in %s", service, name)
+	}
+
+	changed := false
+	if len(s.Environment) > 0 {
+		// Inline values were written with $ escaped as $$
+		legacy, err := utils.ParseEnvList(environmentList(s.Environment))
+		if err != nil {
+			return err
+		}
+		for _, v := range legacy {
+			if _, ok := env.Get(v.Key); !ok {
+				env.Set(v.Key, strings.ReplaceAll(v.Value, "$$", "$"))
+			}
+		}
+		s.Environment = nil
+		changed = true
+	}
+	file := utils.ServiceEnvFile(name, service)
+	if !slices.ContainsFunc(s.EnvFiles, func(f composetypes.EnvFile) bool { return f.Path == file }) {
+		s.EnvFiles = append(s.EnvFiles, composetypes.EnvFile{Path: file, Required: true})
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+
+	project.Services[service] = s
+	return writeCompose(ctx, name, project)
+}
+
+// environmentList returns the environment of a compose service as sorted KEY=VALUE
+// assignments, or KEY alone for the variables taken from the environment.
+func environmentList(environment composetypes.MappingWithEquals) []string {
+	list := make([]string, 0, len(environment))
+	for key, value := range environment {
+		if value == nil {
+			list = append(list, key)
+			continue
+		}
+		list = append(list, key+"="+*value)
+	}
+	slices.Sort(list)
+	return list
+}
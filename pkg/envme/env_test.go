@@ -0,0 +1,79 @@
+package envme
+
+import (
+	"context"
+	"envme/lib/utils"
+	"reflect"
+	"testing"
+)
+
+func TestMigrateEnv(t *testing.T) {
+	setHome(t)
+	ctx := context.Background()
+	t.Setenv("DEBUG", "1")
+
+	if err := utils.WriteComposeFile("app", []byte(handWritten)); err != nil {
+		t.Fatal(err)
+	}
+	before, err := ReadCompose(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := utils.Env{{Key: "TOKEN", Value: "kept"}}
+	if err := migrateEnv(ctx, "app", "worker", &env); err != nil {
+		t.Fatal(err)
+	}
+	if want := (utils.Env{{Key: "TOKEN", Value: "kept"}, {Key: "DEBUG", Value: "1"}}); !reflect.DeepEqual(env, want) {
+		t.Errorf("env = %q, want %q", env, want)
+	}
+
+	after, err := ReadCompose(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	worker := after.Services["worker"]
+	if len(worker.Environment) != 0 {
+		t.Errorf("environment = %v, want none", worker.Environment)
+	}
+	if len(worker.EnvFiles) != 1 || worker.EnvFiles[0].Path != utils.ServiceEnvFile("app", "worker") {
+		t.Errorf("env files = %+v", worker.EnvFiles)
+	}
+	if !reflect.DeepEqual(worker.SecurityOpt, before.Services["worker"].SecurityOpt) {
+		t.Errorf("security_opt = %v", worker.SecurityOpt)
+	}
+	if !reflect.DeepEqual(after.Services["app"], before.Services["app"]) {
+		t.Errorf("app = %+v, want %+v", after.Services["app"], before.Services["app"])
+	}
+
+	// Once migrated, the compose file is left as is
+	content, err := utils.ReadComposeFile("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateEnv(ctx, "app", "worker", &env); err != nil {
+		t.Fatal(err)
+	}
+	again, err := utils.ReadComposeFile("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(content) {
+		t.Errorf("migrated twice:\n%s", again)
+	}
+
+	env = nil
+	if err := migrateEnv(ctx, "app", "app", &env); err != nil {
+		t.Fatal(err)
+	}
+	after, err = ReadCompose(ctx, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if files := after.Services["app"].EnvFiles; len(files) != 1 || files[0].Path != utils.ServiceEnvFile("app", "app") {
+		t.Errorf("env files of app = %+v", files)
+	}
+	if err := migrateEnv(ctx, "app", "missing", &env); err == nil {
+		t.Error("a missing service was migrated")
+	}
+}
@@ -0,0 +1,31 @@
+package envme
+
+import (
+	"context"
+	"github.com/docker/compose/v2/pkg/api"
+)
+
+// shellProbe starts the first available shell in the container
+const shellProbe = `for s in bash ash sh; do command -v $s >/dev/null 2>&1 && exec $s; done; echo "no shell found" >&2; exit 127`
+
+// Exec runs a command in the running container of the stack and returns its exit code.
+// The container of the service named after the stack is used unless options.Service is set.
+func Exec(ctx context.Context, name string, options api.RunOptions) (int, error) {
+	compose, project, err := LoadStack(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+
+	options.Project = project
+	if options.Service == "" {
+		options.Service = name
+	}
+	return compose.Exec(ctx, project.Name, options)
+}
+
+// Shell opens an interactive shell in the container of the stack, preferring bash, then ash, then sh.
+func Shell(ctx context.Context, name string, options api.RunOptions) (int, error) {
+	options.Command = []string{"/bin/sh", "-c", shellProbe}
+	options.Interactive = true
+	return Exec(ctx, name, options)
+}
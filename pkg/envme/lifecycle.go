@@ -0,0 +1,80 @@
+package envme
+
+import (
+	"context"
+	"envme/lib/utils"
+	"errors"
+	"fmt"
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/compose/v2/pkg/api"
+)
+
+// ResolveStacks returns the given stack names, or every stack under ~/.envme when all is set.
+func ResolveStacks(names []string, all bool) ([]string, error) {
+	if all {
+		return utils.GetListServices()
+	}
+	if len(names) == 0 {
+		return nil, errors.New("please specify at least one service name or use --all")
+	}
+	return names, nil
+}
+
+// EachStack runs fn for every stack, continuing on failure, and returns the joined errors.
+func EachStack(names []string, fn func(name string) error) error {
+	var errs []error
+	for _, name := range names {
+		if err := fn(name); err != nil {
+			fmt.Printf("Error on %s: %v\n", name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LoadStack loads the compose project of an existing stack.
+func LoadStack(ctx context.Context, name string) (api.Service, *types.Project, error) {
+	if !utils.HasService(name) {
+		return nil, nil, fmt.Errorf("service %q not found", name)
+	}
+
+	return newStackCompose(ctx, name)
+}
+
+// Down stops and removes the containers of the stack, and optionally its volumes and images.
+func Down(ctx context.Context, name string, options api.DownOptions) error {
+	compose, project, err := LoadStack(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	options.Project = project
+	return compose.Down(ctx, project.Name, options)
+}
+
+func Start(ctx context.Context, name string) error {
+	compose, project, err := LoadStack(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	return compose.Start(ctx, project.Name, api.StartOptions{Project: project})
+}
+
+func Stop(ctx context.Context, name string) error {
+	compose, project, err := LoadStack(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	return compose.Stop(ctx, project.Name, api.StopOptions{Project: project})
+}
+
+func Restart(ctx context.Context, name string) error {
+	compose, project, err := LoadStack(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	return compose.Restart(ctx, project.Name, api.RestartOptions{Project: project})
+}
@@ -0,0 +1,175 @@
+package envme
+
+import (
+	"context"
+	"encoding/json"
+	"envme/lib/docker"
+	"envme/lib/types"
+	"envme/lib/utils"
+	"fmt"
+	"github.com/docker/compose/v2/pkg/api"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/go-units"
+	"gopkg.in/yaml.v2"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ListServices returns the status of every stack under ~/.envme joined with
+// the state of its containers. A stack whose metadata cannot be read is
+// reported in the unknown state with its error, without failing the others.
+func ListServices(ctx context.Context) ([]*types.StackStatus, error) {
+	names, err := utils.GetListServices()
+	if err != nil {
+		return nil, err
+	}
+
+	cli, err := docker.NewClient()
+	if err != nil {
+		return nil, err
+	}
+
+	services := make([]*types.StackStatus, 0, len(names))
+	for _, name := range names {
+		status, err := getStackStatus(ctx, cli, name)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, status)
+	}
+	return services, nil
+}
+
+// UserStacks returns the names of the stacks created by the user, leaving out
+// the ones managed by envme.
+func UserStacks() ([]string, error) {
+	names, err := utils.GetListServices()
+	if err != nil {
+		return nil, err
+	}
+
+	var stacks []string
+	for _, name := range names {
+		metadata, _ := ReadMetadata(name)
+		if !IsManaged(name, metadata) {
+			stacks = append(stacks, name)
+		}
+	}
+	return stacks, nil
+}
+
+func getStackStatus(ctx context.Context, cli client.APIClient, name string) (*types.StackStatus, error) {
+	status := &types.StackStatus{Name: name, State: types.StateMissing}
+
+	metadata, err := ReadMetadata(name)
+	if err != nil {
+		status.State = types.StateUnknown
+		status.Error = err.Error()
+		status.Image = getComposeImage(ctx, name)
+		return status, nil
+	}
+	status.Kind = metadata.Kind
+	status.Expose = metadata.Expose
+	status.Local = metadata.Local
+
+	containers, err := docker.ListContainers(ctx, cli, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(containers) == 0 {
+		status.Image = metadata.Image
+		if status.Image == "" {
+			status.Image = getComposeImage(ctx, name)
+		}
+		return status, nil
+	}
+
+	// The container named after the stack is the primary one
+	primary := containers[0]
+	for _, c := range containers {
+		if c.Labels[api.ServiceLabel] == name {
+			primary = c
+			break
+		}
+	}
+
+	status.State = primary.State
+	status.Image = primary.Image
+	if primary.State == types.StateRunning {
+		startedAt, err := docker.GetStartedAt(ctx, cli, primary.ID)
+		if err == nil {
+			status.Uptime = units.HumanDuration(time.Since(startedAt))
+		}
+	}
+	for _, c := range containers {
+		status.Ports = append(status.Ports, formatPorts(c.Ports)...)
+	}
+	return status, nil
+}
+
+// getComposeImage reads the image of a stack that has no containers from its compose file.
+func getComposeImage(ctx context.Context, name string) string {
+	project, err := ReadCompose(ctx, name)
+	if err != nil {
+		return ""
+	}
+
+	srv, ok := project.Services[name]
+	if !ok {
+		return ""
+	}
+	if srv.Image == "" && srv.Build != nil {
+		return "build:" + srv.Build.Context
+	}
+	return srv.Image
+}
+
+func formatPorts(ports []dockertypes.Port) []string {
+	var formatted []string
+	for _, p := range ports {
+		if p.PublicPort == 0 {
+			formatted = append(formatted, fmt.Sprintf("%d/%s", p.PrivatePort, p.Type))
+			continue
+		}
+		formatted = append(formatted, fmt.Sprintf("%s:%d->%d/%s", p.IP, p.PublicPort, p.PrivatePort, p.Type))
+	}
+	return formatted
+}
+
+// WriteServices prints the services in the given format: table, json or yaml.
+func WriteServices(w io.Writer, services []*types.StackStatus, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(services)
+	case "yaml":
+		content, err := yaml.Marshal(services)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
+		return err
+	case "table", "":
+		tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "NAME\tKIND\tSTATE\tIMAGE\tUPTIME\tPORTS\tEXPOSE")
+		for _, s := range services {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Name, s.Kind, s.State, s.Image, s.Uptime, strings.Join(s.Ports, ", "), strings.Join(s.ExposeHostnames(), ", "))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		for _, s := range services {
+			if s.Error != "" {
+				_, _ = fmt.Fprintf(w, "\nError on %s: %s\n", s.Name, s.Error)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q, expected table, json or yaml", format)
+	}
+}
@@ -0,0 +1,54 @@
+package envme
+
+import (
+	"context"
+	"envme/lib/docker"
+	"errors"
+	"fmt"
+	"github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/compose/v2/pkg/api"
+	"os"
+	"sync"
+)
+
+// Logs prints the container logs of the stacks, interleaving them when there
+// are several, until they end or, when following, until the context is done.
+func Logs(ctx context.Context, names []string, options api.LogOptions) error {
+	consumer := docker.NewLogConsumer(os.Stdout, os.Stderr, len(names) > 1)
+
+	// Resolve every stack first so a bad name fails before any log is streamed
+	type stack struct {
+		compose api.Service
+		project *types.Project
+	}
+	stacks := make([]stack, 0, len(names))
+	for _, name := range names {
+		compose, project, err := LoadStack(ctx, name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		stacks = append(stacks, stack{compose, project})
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for _, s := range stacks {
+		wg.Add(1)
+		go func(s stack, options api.LogOptions) {
+			defer wg.Done()
+			options.Project = s.project
+			err := s.compose.Logs(ctx, s.project.Name, consumer, options)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", s.project.Name, err))
+				mu.Unlock()
+			}
+		}(s, options)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,57 @@
+package envme
+
+import (
+	"envme/lib/types"
+	"envme/lib/utils"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"os"
+	"time"
+)
+
+const metadataFile = "envme.yaml"
+
+// Version is the envme release recorded in the metadata of new stacks.
+var Version = "dev"
+
+// NewMetadata returns the metadata of a stack created now.
+func NewMetadata(kind string) *types.Metadata {
+	return &types.Metadata{
+		Version:      types.MetadataVersion,
+		Kind:         kind,
+		CreatedAt:    time.Now().UTC().Truncate(time.Second),
+		EnvmeVersion: Version,
+	}
+}
+
+// ReadMetadata loads the envme.yaml of the stack. Stacks created before the
+// metadata existed get an empty document without kind.
+func ReadMetadata(name string) (*types.Metadata, error) {
+	content, err := utils.ReadServiceFile(name, metadataFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return &types.Metadata{Version: types.MetadataVersion}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &types.Metadata{}
+	if err := yaml.Unmarshal(content, metadata); err != nil {
+		return nil, fmt.Errorf("invalid %s of %s: %w", metadataFile, name, err)
+	}
+	if metadata.Version > types.MetadataVersion {
+		return nil, fmt.Errorf("%s of %s has version %d, this envme supports up to %d, please upgrade envme", metadataFile, name, metadata.Version, types.MetadataVersion)
+	}
+	metadata.Version = types.MetadataVersion
+	return metadata, nil
+}
+
+func WriteMetadata(name string, metadata *types.Metadata) error {
+	content, err := yaml.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteServiceFile(name, metadataFile, content, 0644)
+}
@@ -0,0 +1,85 @@
+package envme
+
+import (
+	"context"
+	"envme/lib/docker"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// EnsureNetwork creates the envme network from the network-config settings when it is missing.
+func EnsureNetwork(ctx context.Context, network string) error {
+	cli, err := docker.NewClient()
+	if err != nil {
+		return err
+	}
+
+	return docker.EnsureNetworkExists(ctx, cli, network, docker.NetworkOptions{
+		Driver: viper.GetString("network-config.driver"),
+		Subnet: viper.GetString("network-config.subnet"),
+		Labels: viper.GetStringMapString("network-config.labels"),
+	})
+}
+
+// InspectNetwork prints the details of the network and the containers attached to it.
+func InspectNetwork(ctx context.Context, w io.Writer, network string) error {
+	cli, err := docker.NewClient()
+	if err != nil {
+		return err
+	}
+
+	info, found, err := docker.FindNetwork(ctx, cli, network)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("network %q not found, it is created with the first service", network)
+	}
+
+	var subnets []string
+	for _, c := range info.IPAM.Config {
+		subnets = append(subnets, c.Subnet)
+	}
+	var labels []string
+	for k, v := range info.Labels {
+		labels = append(labels, k+"="+v)
+	}
+	sort.Strings(labels)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintf(tw, "Name:\t%s\n", info.Name)
+	_, _ = fmt.Fprintf(tw, "ID:\t%s\n", info.ID)
+	_, _ = fmt.Fprintf(tw, "Driver:\t%s\n", info.Driver)
+	_, _ = fmt.Fprintf(tw, "Subnet:\t%s\n", strings.Join(subnets, ", "))
+	_, _ = fmt.Fprintf(tw, "Labels:\t%s\n", strings.Join(labels, ", "))
+	_, _ = fmt.Fprintf(tw, "Containers:\t%d\n", len(info.Containers))
+	for _, c := range info.Containers {
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\n", c.Name, c.IPv4Address)
+	}
+	return tw.Flush()
+}
+
+// PruneNetworks removes the unused networks created by envme.
+func PruneNetworks(ctx context.Context) error {
+	cli, err := docker.NewClient()
+	if err != nil {
+		return err
+	}
+
+	removed, err := docker.PruneNetworks(ctx, cli)
+	if err != nil {
+		return err
+	}
+
+	if len(removed) == 0 {
+		fmt.Println("No unused networks to remove")
+	}
+	for _, name := range removed {
+		fmt.Printf("Removed network %s\n", name)
+	}
+	return nil
+}
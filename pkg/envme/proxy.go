@@ -0,0 +1,214 @@
+package envme
+
+import (
+	"cmp"
+	"context"
+	"envme/lib/docker"
+	"envme/lib/types"
+	"envme/lib/utils"
+	"fmt"
+	composetypes "github.com/compose-spec/compose-go/v2/types"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/spf13/viper"
+	"strings"
+)
+
+const (
+	ProxyStack = "traefik"
+	proxyImage = "traefik:v3.0"
+)
+
+// IsManaged reports whether the stack is run by envme itself, as the tunnel or the proxy.
+func IsManaged(name string, metadata *types.Metadata) bool {
+	if name == TunnelStack || name == ProxyStack {
+		return true
+	}
+	return metadata != nil && (metadata.Kind == types.KindTunnel || metadata.Kind == types.KindProxy)
+}
+
+// LocalHostname returns the default local hostname of the stack, <name>.<proxy.domain>,
+// <name>.localhost when no domain is set.
+func LocalHostname(name string) string {
+	return name + "." + cmp.Or(strings.Trim(viper.GetString("proxy.domain"), "."), "localhost")
+}
+
+// ExposeLocal routes the hostname, <name>.localhost by default, to the port of
+// the given stack through the envme proxy.
+func ExposeLocal(ctx context.Context, name, port, hostname, network string) error {
+	if !utils.HasService(name) {
+		return fmt.Errorf("service %q not found", name)
+	}
+	metadata, err := ReadMetadata(name)
+	if err != nil {
+		return err
+	}
+	if IsManaged(name, metadata) {
+		return fmt.Errorf("%s is managed by envme and cannot be exposed", name)
+	}
+	if hostname == "" {
+		hostname = LocalHostname(name)
+	}
+	rule, err := NewExposeRule(port, hostname)
+	if err != nil {
+		return err
+	}
+
+	err = SaveLocalRules(name, rule)
+	if err != nil {
+		fmt.Printf("Error saving local rules: %v\n", err)
+		return err
+	}
+
+	err = upProxy(ctx, network)
+	if err != nil {
+		return err
+	}
+
+	// The routes are labels of the container, which only change when it is recreated
+	err = recreate(ctx, name, api.RecreateForce, name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Exposing %s:%d on https://%s%s\n", name, rule.Port, rule.Hostname, proxyPortSuffix("proxy.https-port", 443))
+	return nil
+}
+
+// SaveLocalRules adds the rules to the metadata of the stack, replacing any rule with the same hostname.
+func SaveLocalRules(name string, rules ...*types.ExposeRule) error {
+	metadata, err := ReadMetadata(name)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		found := false
+		for _, r := range metadata.Local {
+			if r.Hostname == rule.Hostname {
+				r.Port = rule.Port
+				found = true
+				break
+			}
+		}
+		if !found {
+			metadata.Local = append(metadata.Local, rule)
+		}
+	}
+
+	return WriteMetadata(name, metadata)
+}
+
+// localRoutes returns the proxy routes recorded in the metadata of the stack.
+func localRoutes(name string) ([]docker.Route, error) {
+	metadata, err := ReadMetadata(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var routes []docker.Route
+	for _, rule := range metadata.Local {
+		routes = append(routes, docker.Route{Hostname: rule.Hostname, Port: rule.Port})
+	}
+	return routes, nil
+}
+
+// newStackCompose loads the compose project of the stack with its proxy routes.
+func newStackCompose(ctx context.Context, name string) (api.Service, *composetypes.Project, error) {
+	routes, err := localRoutes(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return docker.NewCompose(ctx, name, routes...)
+}
+
+// proxyPortSuffix returns the :<port> suffix of the local URLs for the proxy
+// port config key, empty for the default port of the scheme.
+func proxyPortSuffix(key string, standard int) string {
+	port := viper.GetInt(key)
+	if port == standard {
+		return ""
+	}
+	return fmt.Sprintf(":%d", port)
+}
+
+// upProxy writes the compose file of the proxy stack and starts it. The proxy
+// discovers the routes from the labels of the containers on the network and
+// serves the certificates issued by the local CA for the local hostnames.
+func upProxy(ctx context.Context, network string) error {
+	metadata, err := ReadMetadata(ProxyStack)
+	if err != nil {
+		return err
+	}
+	if metadata.Kind == "" {
+		metadata = NewMetadata(types.KindProxy)
+		metadata.Image = proxyImage
+	}
+
+	hostnames, err := localHostnames()
+	if err != nil {
+		return err
+	}
+	certsDir, err := issueLocalCerts(hostnames)
+	if err != nil {
+		fmt.Printf("Error issuing certificates: %v\n", err)
+		return err
+	}
+
+	publish := []*types.PublishRule{
+		{Host: viper.GetInt("proxy.http-port"), Container: 80},
+		{Host: viper.GetInt("proxy.https-port"), Container: 443},
+	}
+	err = ResolvePublish(ctx, ProxyStack, publish)
+	if err != nil {
+		return err
+	}
+	metadata.Publish = publish
+
+	project, err := toProject(ctx, ProxyStack, newCompose(ProxyStack, network, &types.Service{
+		ContainerName: ProxyStack,
+		Image:         proxyImage,
+		Restart:       "unless-stopped",
+		Command: types.ShellCommand{
+			"--providers.docker=true",
+			"--providers.docker.exposedbydefault=false",
+			"--providers.docker.network=" + network,
+			"--providers.file.directory=" + proxyCertsPath,
+			"--providers.file.watch=true",
+			"--entrypoints.web.address=:80",
+			"--entrypoints.websecure.address=:443",
+		},
+		Ports: publishSpecs(publish),
+		Volumes: []string{
+			"/var/run/docker.sock:/var/run/docker.sock:ro",
+			certsDir + ":" + proxyCertsPath + ":ro",
+		},
+		Networks: &[]string{network},
+	}, nil))
+	if err != nil {
+		return err
+	}
+	err = writeCompose(ctx, ProxyStack, project)
+	if err != nil {
+		return err
+	}
+
+	err = WriteMetadata(ProxyStack, metadata)
+	if err != nil {
+		fmt.Printf("Error writing metadata: %v\n", err)
+		return err
+	}
+
+	err = EnsureNetwork(ctx, network)
+	if err != nil {
+		fmt.Printf("Error creating network: %v\n", err)
+		return err
+	}
+
+	compose, project, err := docker.NewCompose(ctx, ProxyStack)
+	if err != nil {
+		fmt.Printf("Error creating compose: %v\n", err)
+		return err
+	}
+
+	return compose.Up(ctx, project, api.UpOptions{})
+}
@@ -0,0 +1,28 @@
+package envme
+
+import (
+	"github.com/spf13/viper"
+	"testing"
+)
+
+func TestLocalHostname(t *testing.T) {
+	t.Cleanup(func() { viper.Set("proxy.domain", nil) })
+
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{domain: "", want: "api.localhost"},
+		{domain: "localhost", want: "api.localhost"},
+		{domain: ".test", want: "api.test"},
+		{domain: "dev.example.com.", want: "api.dev.example.com"},
+		{domain: ".", want: "api.localhost"},
+	}
+
+	for _, tt := range tests {
+		viper.Set("proxy.domain", tt.domain)
+		if got := LocalHostname("api"); got != tt.want {
+			t.Errorf("LocalHostname() with domain %q = %q, want %q", tt.domain, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package envme
+
+import (
+	"cmp"
+	"context"
+	"envme/lib/docker"
+	"envme/lib/types"
+	"envme/lib/utils"
+	"errors"
+	"fmt"
+	"github.com/docker/compose/v2/pkg/api"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// autoPortRange is how many ports after the container port are tried when
+// assigning a host port, before asking the system for any free one.
+const autoPortRange = 100
+
+// ParsePublishRule parses a publish spec in the format [[<host-ip>:]<host-port>:]<container-port>[/<protocol>],
+// an IPv6 host ip in brackets as in [::1]:8080:80.
+// Without host port, or with host port 0, a free one is assigned on creation.
+func ParsePublishRule(spec string) (*types.PublishRule, error) {
+	ports, protocol, _ := strings.Cut(strings.TrimSpace(spec), "/")
+	if protocol != "" && protocol != "tcp" && protocol != "udp" {
+		return nil, fmt.Errorf("invalid publish %q, protocol must be tcp or udp", spec)
+	}
+
+	rule := &types.PublishRule{Protocol: protocol}
+	// An IPv6 host ip is written in brackets, its colons are not separators
+	if rest, ok := strings.CutPrefix(ports, "["); ok {
+		ip, rest, _ := strings.Cut(rest, "]")
+		if !strings.Contains(ip, ":") || net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("invalid publish %q, invalid host ip %q", spec, ip)
+		}
+		ports, ok = strings.CutPrefix(rest, ":")
+		if !ok || strings.Count(ports, ":") != 1 {
+			return nil, fmt.Errorf("invalid publish %q, expected [<host-ip>]:<host-port>:<container-port>", spec)
+		}
+		rule.HostIP = ip
+	}
+
+	var host, container string
+	parts := strings.Split(ports, ":")
+	switch len(parts) {
+	case 1:
+		container = parts[0]
+	case 2:
+		host, container = parts[0], parts[1]
+	case 3:
+		rule.HostIP, host, container = parts[0], parts[1], parts[2]
+		if net.ParseIP(rule.HostIP) == nil {
+			return nil, fmt.Errorf("invalid publish %q, invalid host ip %q", spec, rule.HostIP)
+		}
+	default:
+		return nil, fmt.Errorf("invalid publish %q, expected [[<host-ip>:]<host-port>:]<container-port>, with an IPv6 host ip in brackets", spec)
+	}
+
+	var err error
+	rule.Container, err = parsePort(container, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid publish %q: %w", spec, err)
+	}
+	rule.Host, err = parsePort(host, true)
+	if err != nil {
+		return nil, fmt.Errorf("invalid publish %q: %w", spec, err)
+	}
+	return rule, nil
+}
+
+func parsePort(port string, auto bool) (int, error) {
+	if auto && (port == "" || port == "0") {
+		return 0, nil
+	}
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 1 || p > 65535 {
+		return 0, fmt.Errorf("invalid port %q", port)
+	}
+	return p, nil
+}
+
+// ParsePublishRules parses every non-empty publish spec.
+func ParsePublishRules(specs []string) ([]*types.PublishRule, error) {
+	var rules []*types.PublishRule
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		rule, err := ParsePublishRule(spec)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// fragmentPublish appends the ports of the compose fragment of a catalog entry or
+// a template to the rules, for them to be checked and assigned like --publish.
+func fragmentPublish(rules []*types.PublishRule, fragment *types.Service) ([]*types.PublishRule, error) {
+	if fragment == nil {
+		return rules, nil
+	}
+	ports, err := ParsePublishRules(fragment.Ports)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ports of the compose fragment: %w", err)
+	}
+	return append(rules, ports...), nil
+}
+
+// publishSpecs returns the rules in the compose ports format.
+func publishSpecs(rules []*types.PublishRule) []string {
+	var specs []string
+	for _, rule := range rules {
+		specs = append(specs, rule.String())
+	}
+	return specs
+}
+
+// ResolvePublish checks that the host ports of the rules are not published by
+// another envme stack or container and not listened on by another process,
+// and assigns a free host port to the rules without one.
+func ResolvePublish(ctx context.Context, name string, rules []*types.PublishRule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	used, own, err := publishedPorts(ctx, name)
+	if err != nil {
+		return err
+	}
+	return resolvePublish(name, rules, used, own)
+}
+
+// resolvePublish resolves the rules of the stack against the host ports used
+// by others, with their owner, and the ones published by the stack itself.
+// The stack may publish its own ports again, when it is recreated.
+func resolvePublish(name string, rules []*types.PublishRule, used map[string]string, own map[string]bool) error {
+	var err error
+	for _, rule := range rules {
+		if rule.Host == 0 {
+			rule.Host, err = freePort(rule, used)
+			if err != nil {
+				return err
+			}
+			rule.Auto = true
+			fmt.Printf("Publishing %d on host port %d\n", rule.Container, rule.Host)
+		} else if owner, ok := used[portKey(rule.Network(), rule.Host)]; ok {
+			return fmt.Errorf("host port %d is already published by %s, omit the host port to assign a free one", rule.Host, owner)
+		} else if !own[portKey(rule.Network(), rule.Host)] && !portAvailable(rule) {
+			return fmt.Errorf("host port %d is already in use on this machine, omit the host port to assign a free one", rule.Host)
+		}
+		used[portKey(rule.Network(), rule.Host)] = name
+	}
+	return nil
+}
+
+// SavePublishRules adds the rules to the metadata of the stack, replacing the
+// ones of the same service and container port.
+func SavePublishRules(name string, rules ...*types.PublishRule) error {
+	metadata, err := ReadMetadata(name)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		found := false
+		for i, r := range metadata.Publish {
+			if r.Service == rule.Service && r.Container == rule.Container && r.Network() == rule.Network() {
+				metadata.Publish[i] = rule
+				found = true
+				break
+			}
+		}
+		if !found {
+			metadata.Publish = append(metadata.Publish, rule)
+		}
+	}
+
+	return WriteMetadata(name, metadata)
+}
+
+func portKey(network string, port int) string {
+	return network + "/" + strconv.Itoa(port)
+}
+
+// publishedRange returns the host ports of a published port or port range of a compose file.
+func publishedRange(published string) []int {
+	start, end, isRange := strings.Cut(published, "-")
+	first, err := strconv.Atoi(start)
+	if err != nil || first == 0 {
+		return nil
+	}
+	last := first
+	if isRange {
+		last, err = strconv.Atoi(end)
+		if err != nil || last < first {
+			return nil
+		}
+	}
+	ports := make([]int, 0, last-first+1)
+	for port := first; port <= last; port++ {
+		ports = append(ports, port)
+	}
+	return ports
+}
+
+// publishedPorts returns the host ports published by the other envme stacks,
+// running or not, and by running containers of other projects, with their owner.
+// It also returns the ports published by the running containers of the stack itself.
+func publishedPorts(ctx context.Context, name string) (map[string]string, map[string]bool, error) {
+	used := map[string]string{}
+	own := map[string]bool{}
+
+	stacks, err := utils.GetListServices()
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, stack := range stacks {
+		if stack == name {
+			continue
+		}
+		metadata, err := ReadMetadata(stack)
+		if err == nil {
+			for _, rule := range metadata.Publish {
+				used[portKey(rule.Network(), rule.Host)] = "stack " + stack
+			}
+		}
+		// Stacks may also publish ports written by hand in their compose file
+		project, err := ReadCompose(ctx, stack)
+		if err != nil {
+			continue
+		}
+		for _, service := range project.Services {
+			for _, port := range service.Ports {
+				for _, host := range publishedRange(port.Published) {
+					used[portKey(cmp.Or(port.Protocol, "tcp"), host)] = "stack " + stack
+				}
+			}
+		}
+	}
+
+	cli, err := docker.NewClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	containers, err := docker.ListPublishedPorts(ctx, cli)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, c := range containers {
+		for _, p := range c.Ports {
+			if p.PublicPort == 0 {
+				continue
+			}
+			key := portKey(p.Type, int(p.PublicPort))
+			if c.Labels[api.ProjectLabel] == name {
+				own[key] = true
+				continue
+			}
+			if _, ok := used[key]; !ok {
+				used[key] = "container " + strings.TrimPrefix(c.Names[0], "/")
+			}
+		}
+	}
+	return used, own, nil
+}
+
+// freePort returns the first host port from the container port on that is not
+// published and can be listened on, or any free port the system picks.
+func freePort(rule *types.PublishRule, used map[string]string) (int, error) {
+	candidate := *rule
+	for port := rule.Container; port < rule.Container+autoPortRange && port <= 65535; port++ {
+		if _, ok := used[portKey(rule.Network(), port)]; ok {
+			continue
+		}
+		candidate.Host = port
+		if portAvailable(&candidate) {
+			return port, nil
+		}
+	}
+
+	if rule.Network() == "udp" {
+		conn, err := net.ListenPacket("udp", rule.HostIP+":0")
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close()
+		return conn.LocalAddr().(*net.UDPAddr).Port, nil
+	}
+	listener, err := net.Listen("tcp", rule.HostIP+":0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// portAvailable reports whether the host port of the rule can be listened on.
+// Privileged ports envme is not allowed to bind are left to the Docker daemon.
+func portAvailable(rule *types.PublishRule) bool {
+	var err error
+	if rule.Network() == "udp" {
+		var conn net.PacketConn
+		conn, err = net.ListenPacket("udp", rule.Address())
+		if err == nil {
+			_ = conn.Close()
+		}
+	} else {
+		var listener net.Listener
+		listener, err = net.Listen("tcp", rule.Address())
+		if err == nil {
+			_ = listener.Close()
+		}
+	}
+	return err == nil || errors.Is(err, os.ErrPermission)
+}
@@ -0,0 +1,262 @@
+package envme
+
+import (
+	"envme/lib/types"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePublishRule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    *types.PublishRule
+		wantErr bool
+	}{
+		{spec: "80", want: &types.PublishRule{Container: 80}},
+		{spec: "0:80", want: &types.PublishRule{Container: 80}},
+		{spec: "8080:80", want: &types.PublishRule{Host: 8080, Container: 80}},
+		{spec: " 8080:80/udp ", want: &types.PublishRule{Host: 8080, Container: 80, Protocol: "udp"}},
+		{spec: "127.0.0.1:8080:80", want: &types.PublishRule{HostIP: "127.0.0.1", Host: 8080, Container: 80}},
+		{spec: "127.0.0.1::80", want: &types.PublishRule{HostIP: "127.0.0.1", Container: 80}},
+		{spec: "[::1]:8080:80", want: &types.PublishRule{HostIP: "::1", Host: 8080, Container: 80}},
+		{spec: "[fe80::1]:53:53/udp", want: &types.PublishRule{HostIP: "fe80::1", Host: 53, Container: 53, Protocol: "udp"}},
+		{spec: "[::1]::80", want: &types.PublishRule{HostIP: "::1", Container: 80}},
+		{spec: "::1:8080:80", wantErr: true},
+		{spec: "[::1]:80", wantErr: true},
+		{spec: "[::1]8080:80", wantErr: true},
+		{spec: "[127.0.0.1]:8080:80", wantErr: true},
+		{spec: "[::1:8080:80", wantErr: true},
+		{spec: "localhost:8080:80", wantErr: true},
+		{spec: "8080:80/sctp", wantErr: true},
+		{spec: "8080:", wantErr: true},
+		{spec: "web", wantErr: true},
+		{spec: "70000:80", wantErr: true},
+		{spec: "3000-3001:3000-3001", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePublishRule(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePublishRule(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParsePublishRule(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestPublishRuleString(t *testing.T) {
+	for _, spec := range []string{"8080:80", "127.0.0.1:8080:80", "[::1]:8080:80", "53:53/udp"} {
+		rule, err := ParsePublishRule(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rule.String(); got != spec {
+			t.Errorf("String() = %q, want %q", got, spec)
+		}
+	}
+}
+
+// listen holds a host port of the loopback interface for the test.
+func listen(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// freed returns a host port of the loopback interface that was just free.
+func freed(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestPortAvailable(t *testing.T) {
+	busy := listen(t)
+	if portAvailable(&types.PublishRule{HostIP: "127.0.0.1", Host: busy}) {
+		t.Errorf("port %d in use is available", busy)
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	udp := conn.LocalAddr().(*net.UDPAddr).Port
+	if portAvailable(&types.PublishRule{HostIP: "127.0.0.1", Host: udp, Protocol: "udp"}) {
+		t.Errorf("udp port %d in use is available", udp)
+	}
+	_ = conn.Close()
+	if !portAvailable(&types.PublishRule{HostIP: "127.0.0.1", Host: udp, Protocol: "udp"}) {
+		t.Errorf("udp port %d is not available once closed", udp)
+	}
+}
+
+func TestFreePort(t *testing.T) {
+	busy := listen(t)
+
+	tests := []struct {
+		name string
+		rule *types.PublishRule
+		used map[string]string
+	}{
+		{
+			name: "container port in use",
+			rule: &types.PublishRule{HostIP: "127.0.0.1", Container: busy},
+		},
+		{
+			name: "container port published",
+			rule: &types.PublishRule{HostIP: "127.0.0.1", Container: 40000},
+			used: map[string]string{portKey("tcp", 40000): "stack db"},
+		},
+		{
+			name: "range exhausted",
+			rule: &types.PublishRule{HostIP: "127.0.0.1", Container: 65535},
+			used: map[string]string{portKey("tcp", 65535): "stack db"},
+		},
+		{
+			name: "udp",
+			rule: &types.PublishRule{HostIP: "127.0.0.1", Container: 40000, Protocol: "udp"},
+			used: map[string]string{portKey("udp", 40000): "stack db"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			port, err := freePort(tt.rule, tt.used)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if port == tt.rule.Container {
+				t.Errorf("freePort() = %d, the container port is taken", port)
+			}
+			if _, ok := tt.used[portKey(tt.rule.Network(), port)]; ok {
+				t.Errorf("freePort() = %d, published by another stack", port)
+			}
+			candidate := *tt.rule
+			candidate.Host = port
+			if !portAvailable(&candidate) {
+				t.Errorf("freePort() = %d, in use", port)
+			}
+		})
+	}
+}
+
+func TestResolvePublish(t *testing.T) {
+	busy, free := listen(t), freed(t)
+
+	tests := []struct {
+		name    string
+		rules   []*types.PublishRule
+		used    map[string]string
+		own     map[string]bool
+		wantErr string
+	}{
+		{
+			name:  "free port",
+			rules: []*types.PublishRule{{HostIP: "127.0.0.1", Host: free, Container: 80}},
+		},
+		{
+			name:    "published by another stack",
+			rules:   []*types.PublishRule{{Host: 5432, Container: 5432}},
+			used:    map[string]string{portKey("tcp", 5432): "stack db"},
+			wantErr: "already published by stack db",
+		},
+		{
+			name:  "same port on another protocol",
+			rules: []*types.PublishRule{{HostIP: "127.0.0.1", Host: free, Container: 53, Protocol: "udp"}},
+			used:  map[string]string{portKey("tcp", free): "stack db"},
+		},
+		{
+			name:    "in use by another process",
+			rules:   []*types.PublishRule{{HostIP: "127.0.0.1", Host: busy, Container: 80}},
+			wantErr: "already in use on this machine",
+		},
+		{
+			name:  "own port published again",
+			rules: []*types.PublishRule{{HostIP: "127.0.0.1", Host: busy, Container: 80}},
+			own:   map[string]bool{portKey("tcp", busy): true},
+		},
+		{
+			name: "twice in the same stack",
+			rules: []*types.PublishRule{
+				{HostIP: "127.0.0.1", Host: free, Container: 80},
+				{HostIP: "127.0.0.1", Host: free, Container: 81},
+			},
+			wantErr: "already published by web",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			used := tt.used
+			if used == nil {
+				used = map[string]string{}
+			}
+			err := resolvePublish("web", tt.rules, used, tt.own)
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolvePublishAssignsFreePorts(t *testing.T) {
+	rules := []*types.PublishRule{
+		{HostIP: "127.0.0.1", Container: 40000},
+		{HostIP: "127.0.0.1", Container: 40000},
+		{HostIP: "127.0.0.1", Host: freed(t), Container: 80},
+	}
+	used := map[string]string{portKey("tcp", 40000): "stack db"}
+	if err := resolvePublish("web", rules, used, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !rules[0].Auto || !rules[1].Auto || rules[2].Auto {
+		t.Errorf("auto = %v, %v, %v", rules[0].Auto, rules[1].Auto, rules[2].Auto)
+	}
+	if rules[0].Host == 40000 || rules[0].Host == rules[1].Host {
+		t.Errorf("assigned host ports %d and %d", rules[0].Host, rules[1].Host)
+	}
+	for _, rule := range rules {
+		if owner := used[portKey("tcp", rule.Host)]; owner != "web" {
+			t.Errorf("host port %d owned by %q", rule.Host, owner)
+		}
+	}
+}
+
+func TestFragmentPublish(t *testing.T) {
+	rules := []*types.PublishRule{{Host: 8080, Container: 80}}
+	got, err := fragmentPublish(rules, &types.Service{Ports: []string{"9229", "[::1]:5555:5555"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*types.PublishRule{
+		{Host: 8080, Container: 80},
+		{Container: 9229},
+		{HostIP: "::1", Host: 5555, Container: 5555},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("fragmentPublish() = %+v, want %+v", got, want)
+	}
+
+	if _, err := fragmentPublish(nil, &types.Service{Ports: []string{"web"}}); err == nil {
+		t.Error("invalid fragment port accepted")
+	}
+	if got, err := fragmentPublish(rules, nil); err != nil || len(got) != 1 {
+		t.Errorf("fragmentPublish(nil) = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,57 @@
+package envme
+
+import (
+	"context"
+	"envme/lib/utils"
+	"fmt"
+	"github.com/docker/compose/v2/pkg/api"
+	"slices"
+)
+
+// reservedNames are the directories of ~/.envme that are not stacks
+var reservedNames = []string{caDir, templatesDir}
+
+// Remove tears down the stack, drops its tunnel ingress and deletes its directory
+// under ~/.envme. With force, a failing teardown does not prevent the removal.
+func Remove(ctx context.Context, name string, options api.DownOptions, force bool) error {
+	if err := utils.ValidateStackName(name); err != nil {
+		return err
+	}
+	if slices.Contains(reservedNames, name) {
+		return fmt.Errorf("%s is reserved by envme and cannot be removed", name)
+	}
+	if !utils.HasService(name) && !utils.HasServiceFile(name, metadataFile) {
+		return fmt.Errorf("service %q not found", name)
+	}
+
+	metadata, err := ReadMetadata(name)
+	if err != nil && !force {
+		return err
+	}
+	if IsManaged(name, metadata) {
+		return fmt.Errorf("%s is managed by envme, use `envme down %s` instead", name, name)
+	}
+
+	if utils.HasService(name) {
+		err := Down(ctx, name, options)
+		if err != nil && !force {
+			fmt.Printf("Error removing containers: %v\n", err)
+			return err
+		}
+	}
+
+	err = Unexpose(ctx, name)
+	if err != nil && !force {
+		fmt.Printf("Error removing tunnel ingress: %v\n", err)
+		return err
+	}
+
+	err = utils.RemoveServiceDir(name)
+	if err != nil {
+		fmt.Printf("Error removing service directory: %v\n", err)
+		return err
+	}
+
+	fmt.Printf("Removed %s\n", name)
+	return nil
+}
@@ -0,0 +1,87 @@
+package envme
+
+import (
+	"envme/lib/secrets"
+	"envme/lib/utils"
+	"fmt"
+	"github.com/compose-spec/compose-go/v2/types"
+)
+
+// OpenSecrets loads the secrets store under ~/.envme.
+func OpenSecrets() (*secrets.Store, error) {
+	dir, err := utils.GetAppDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return secrets.Open(dir)
+}
+
+func SetSecret(name, value string) error {
+	store, err := OpenSecrets()
+	if err != nil {
+		return err
+	}
+
+	return store.Set(name, value)
+}
+
+func GetSecret(name string) (string, error) {
+	store, err := OpenSecrets()
+	if err != nil {
+		return "", err
+	}
+
+	return store.Get(name)
+}
+
+func RemoveSecret(name string) error {
+	store, err := OpenSecrets()
+	if err != nil {
+		return err
+	}
+
+	return store.Remove(name)
+}
+
+func ListSecrets() ([]string, error) {
+	store, err := OpenSecrets()
+	if err != nil {
+		return nil, err
+	}
+
+	return store.Names(), nil
+}
+
+// resolveSecrets replaces the secret://name values of the environment of the
+// project services with the decrypted secrets. The project is only held in
+// memory, so the plain values never reach ~/.envme.
+func resolveSecrets(project *types.Project) error {
+	var store *secrets.Store
+	for name, service := range project.Services {
+		for key, value := range service.Environment {
+			if value == nil {
+				continue
+			}
+			ref, ok := secrets.ParseRef(*value)
+			if !ok {
+				continue
+			}
+
+			if store == nil {
+				var err error
+				store, err = OpenSecrets()
+				if err != nil {
+					return err
+				}
+			}
+			secret, err := store.Get(ref)
+			if err != nil {
+				return fmt.Errorf("service %s, %s: %w", name, key, err)
+			}
+			service.Environment[key] = &secret
+		}
+		project.Services[name] = service
+	}
+	return nil
+}
@@ -2,116 +2,391 @@ package envme
 
 import (
 	"context"
-	"envme/lib/docker"
+	"envme/lib/catalog"
+	"envme/lib/templates"
 	"envme/lib/types"
 	"envme/lib/utils"
 	"fmt"
+	composetypes "github.com/compose-spec/compose-go/v2/types"
 	"github.com/docker/compose/v2/pkg/api"
 	"github.com/spf13/viper"
-	"gopkg.in/yaml.v2"
+	"path/filepath"
+	"slices"
 )
 
-func CreateService(ctx context.Context, name, image, network string) error {
-	// Create a new Docker Compose file
-	config := &types.Compose{
-		Services: map[string]*types.Service{
-			name: {
-				ContainerName: name,
-				Image:         image,
-				Restart:       "unless-stopped",
-				Environment:   viper.GetStringSlice("env"),
-				Networks:      &[]string{network},
-				ExtraHosts:    &[]string{"host.docker.internal:host-gateway"},
-			},
-		},
-		Networks: map[string]*types.Network{
-			network: {
-				External: true,
-			},
-		},
-	}
-	content, err := yaml.Marshal(config)
+func CreateService(ctx context.Context, name, image, network string, env utils.Env) error {
+	err := checkCreate(name)
 	if err != nil {
-		fmt.Printf("Error marshalling config: %v\n", err)
+		return err
+	}
+	expose, err := ParseExposeRules(viper.GetStringSlice("expose"))
+	if err != nil {
+		fmt.Printf("Error parsing expose: %v\n", err)
+		return err
+	}
+	publish, err := ParsePublishRules(viper.GetStringSlice("publish"))
+	if err != nil {
+		fmt.Printf("Error parsing publish: %v\n", err)
 		return err
 	}
 
-	err = utils.WriteComposeFile(name, content)
+	// Start from the catalog entry when --from is given
+	entry, err := findCatalogEntry(viper.GetString("from"))
 	if err != nil {
-		fmt.Printf("Error writing compose file: %v\n", err)
 		return err
 	}
+	var generated []string
+	if entry != nil {
+		env, generated, err = CatalogEnv(entry, env)
+		if err != nil {
+			return err
+		}
+		publish, err = fragmentPublish(publish, entry.Compose)
+		if err != nil {
+			return err
+		}
+	} else if image == "" {
+		return fmt.Errorf("please specify <image-name> or --from")
+	}
 
-	// Run the Docker Compose file
-	compose, project, err := docker.NewCompose(ctx, name)
+	err = ResolvePublish(ctx, name, publish)
 	if err != nil {
-		fmt.Printf("Error creating compose: %v\n", err)
 		return err
 	}
 
-	// TODO: Expose the port with tunneling
+	// Create a new Docker Compose file
+	config, err := BuildService(ctx, serviceOptions(name, image, network, entry, publish))
+	if err != nil {
+		fmt.Printf("Error building config: %v\n", err)
+		return err
+	}
+	// The .env is written first, the compose file is interpolated from it
+	err = utils.WriteEnvFile(name, utils.EnvFile, env)
+	if err != nil {
+		fmt.Printf("Error writing env file: %v\n", err)
+		return err
+	}
+	err = writeCompose(ctx, name, config)
+	if err != nil {
+		return err
+	}
+	for _, key := range generated {
+		fmt.Printf("Generated %s, see `envme env get %s %s`\n", key, name, key)
+	}
+
+	metadata := NewMetadata(types.KindService)
+	metadata.Image = config.Services[name].Image
+	if entry != nil {
+		metadata.Catalog = entry.Name
+	}
+	metadata.EnvFiles = envFiles()
+	metadata.Publish = publish
+	err = WriteMetadata(name, metadata)
+	if err != nil {
+		fmt.Printf("Error writing metadata: %v\n", err)
+		return err
+	}
 
-	return compose.Up(ctx, project, api.UpOptions{})
+	return up(ctx, name, network, expose)
 }
 
-func CreateDev(ctx context.Context, name, dir, template, network string) error {
-	// Create a new Docker Compose file
-	dir, err := utils.GetAbsPath(dir)
+func CreateDev(ctx context.Context, name, dir, template, network string, env utils.Env) error {
+	err := checkCreate(name)
+	if err != nil {
+		return err
+	}
+	expose, err := ParseExposeRules(viper.GetStringSlice("expose"))
+	if err != nil {
+		fmt.Printf("Error parsing expose: %v\n", err)
+		return err
+	}
+	publish, err := ParsePublishRules(viper.GetStringSlice("publish"))
+	if err != nil {
+		fmt.Printf("Error parsing publish: %v\n", err)
+		return err
+	}
+
+	dir, err = utils.GetAbsPath(dir)
 	if err != nil {
 		fmt.Printf("Error getting absolute path: %v\n", err)
 		return err
 	}
-	fmt.Printf("Creating development environment for %s in %s\n", name, dir)
-	config := &types.Compose{
-		Services: map[string]*types.Service{
-			name: {
-				ContainerName: name,
-				Build: &types.Build{
-					Context:    dir,
-					Dockerfile: "Dockerfile",
-					Target:     "development",
-				},
-				Restart:     "unless-stopped",
-				Environment: viper.GetStringSlice("env"),
-				Networks:    &[]string{network},
-				ExtraHosts:  &[]string{"host.docker.internal:host-gateway"},
-			},
-		},
-		Networks: map[string]*types.Network{
-			network: {
-				External: true,
-			},
-		},
-	}
-	content, err := yaml.Marshal(config)
+
+	// Render the Dockerfile when template is not empty
+	if template == "(none)" {
+		template = ""
+	}
+	if template == "" && !viper.GetBool("no-detect") && !utils.HasDockerfile(dir) {
+		detection, detected := DetectTemplate(dir)
+		switch {
+		case detection == nil:
+			return fmt.Errorf("no Dockerfile in %s and no framework detected, use --template to generate one", dir)
+		case detected == nil:
+			return fmt.Errorf("no Dockerfile in %s, detected %s (%s) but there is no %q template, create it with `envme template new %s`", dir, detection.Framework, detection.Reason, detection.Template, detection.Template)
+		}
+		fmt.Printf("Detected %s (%s), using template %s\n", detection.Framework, detection.Reason, detected.Name)
+		template = detected.Name
+	}
+	var (
+		tmpl       *templates.Template
+		dockerfile []byte
+	)
+	if template != "" {
+		tmpl, err = FindTemplate(template, dir)
+		if err != nil {
+			return err
+		}
+		params, err := templates.ParseParams(viper.GetStringSlice("param"))
+		if err != nil {
+			return err
+		}
+		dockerfile, err = tmpl.Render(params)
+		if err != nil {
+			fmt.Printf("Error rendering template: %v\n", err)
+			return err
+		}
+		template = tmpl.Name
+		publish, err = fragmentPublish(publish, tmpl.Compose)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = ResolvePublish(ctx, name, publish)
 	if err != nil {
-		fmt.Printf("Error marshalling config: %v\n", err)
 		return err
 	}
 
-	err = utils.WriteComposeFile(name, content)
+	// Create a new Docker Compose file
+	fmt.Printf("Creating development environment for %s in %s\n", name, dir)
+	config, err := BuildDevelopment(ctx, developmentOptions(name, dir, network, tmpl, publish))
 	if err != nil {
-		fmt.Printf("Error writing compose file: %v\n", err)
+		fmt.Printf("Error building config: %v\n", err)
 		return err
 	}
 
-	// Write dockerfile when template is not empty
-	if template != "" && template != "(none)" {
-		err = utils.WriteDockerfile(dir, template)
+	// Write dockerfile when template is not empty, it is never replaced so
+	// an existing one stops the creation before the stack files are written
+	if template != "" {
+		fmt.Printf("Writing Dockerfile from template %s to %s\n", template, dir)
+		err = utils.WriteDockerfile(dir, dockerfile)
 		if err != nil {
 			fmt.Printf("Error writing Dockerfile: %v\n", err)
 			return err
 		}
 	}
 
-	// Run the Docker Compose file
-	compose, project, err := docker.NewCompose(ctx, name)
+	// The .env is written first, the compose file is interpolated from it
+	err = utils.WriteEnvFile(name, utils.EnvFile, env)
+	if err != nil {
+		fmt.Printf("Error writing env file: %v\n", err)
+		return err
+	}
+	err = writeCompose(ctx, name, config)
+	if err != nil {
+		return err
+	}
+
+	metadata := NewMetadata(types.KindDevelopment)
+	metadata.Dir = dir
+	metadata.Template = template
+	metadata.EnvFiles = envFiles()
+	metadata.Publish = publish
+	err = WriteMetadata(name, metadata)
+	if err != nil {
+		fmt.Printf("Error writing metadata: %v\n", err)
+		return err
+	}
+
+	return up(ctx, name, network, expose)
+}
+
+// checkCreate rejects the names a new stack cannot take: invalid ones, the
+// directories and stacks of envme itself, and existing stacks unless --force.
+func checkCreate(name string) error {
+	if err := utils.ValidateStackName(name); err != nil {
+		return err
+	}
+	if slices.Contains(reservedNames, name) {
+		return fmt.Errorf("%s is reserved by envme", name)
+	}
+	var metadata *types.Metadata
+	if utils.HasServiceFile(name, metadataFile) {
+		var err error
+		metadata, err = ReadMetadata(name)
+		if err != nil {
+			return err
+		}
+	}
+	if IsManaged(name, metadata) {
+		return fmt.Errorf("%s is managed by envme", name)
+	}
+	if utils.HasService(name) && !viper.GetBool("force") {
+		return fmt.Errorf("service %q already exists, use --force to overwrite it", name)
+	}
+	return nil
+}
+
+// findCatalogEntry returns the catalog entry of --from, nil for none.
+func findCatalogEntry(from string) (*catalog.Entry, error) {
+	if from == "" || from == "(none)" {
+		return nil, nil
+	}
+	return catalog.Find(from)
+}
+
+func serviceOptions(name, image, network string, entry *catalog.Entry, publish []*types.PublishRule) ServiceOptions {
+	return ServiceOptions{
+		Name:    name,
+		Image:   image,
+		Network: network,
+		Volumes: viper.GetStringSlice("volume"),
+		Ports:   publishSpecs(publish),
+		Catalog: entry,
+	}
+}
+
+func developmentOptions(name, dir, network string, tmpl *templates.Template, publish []*types.PublishRule) DevelopmentOptions {
+	return DevelopmentOptions{
+		Name:      name,
+		Dir:       dir,
+		Network:   network,
+		MountPath: viper.GetString("mount-path"),
+		Volumes:   viper.GetStringSlice("volume"),
+		Ports:     publishSpecs(publish),
+		Template:  tmpl,
+	}
+}
+
+// PreviewService returns the docker-compose.yaml CreateService writes for the flags and the publish specs.
+// Host ports left to envme are only assigned on creation, the file is not validated.
+func PreviewService(ctx context.Context, name, image, network, from string, publish []string) ([]byte, error) {
+	entry, err := findCatalogEntry(from)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := ParsePublishRules(publish)
+	if err != nil {
+		return nil, err
+	}
+	if entry != nil {
+		rules, err = fragmentPublish(rules, entry.Compose)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	project, err := BuildService(ctx, serviceOptions(name, image, network, entry, rules))
+	if err != nil {
+		return nil, err
+	}
+	return MarshalCompose(project)
+}
+
+// PreviewDev returns the docker-compose.yaml CreateDev writes for the flags and the publish specs.
+// Host ports left to envme are only assigned on creation, the file is not validated.
+func PreviewDev(ctx context.Context, name, dir, template, network string, publish []string) ([]byte, error) {
+	if template == "(none)" {
+		template = ""
+	}
+	if template == "" && !viper.GetBool("no-detect") && !utils.HasDockerfile(dir) {
+		if _, detected := DetectTemplate(dir); detected != nil {
+			template = detected.Name
+		}
+	}
+	var tmpl *templates.Template
+	if template != "" {
+		var err error
+		tmpl, err = FindTemplate(template, dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rules, err := ParsePublishRules(publish)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl != nil {
+		rules, err = fragmentPublish(rules, tmpl.Compose)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	project, err := BuildDevelopment(ctx, developmentOptions(name, dir, network, tmpl, rules))
+	if err != nil {
+		return nil, err
+	}
+	return MarshalCompose(project)
+}
+
+// envFiles returns the absolute paths of the --env-file files.
+func envFiles() []string {
+	var files []string
+	for _, file := range viper.GetStringSlice("env-file") {
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// ReadCompose reads the docker-compose.yaml of the stack as written.
+func ReadCompose(ctx context.Context, name string) (*composetypes.Project, error) {
+	content, err := utils.ReadComposeFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCompose(ctx, name, content)
+}
+
+// writeCompose validates and writes the docker-compose.yaml of the stack.
+// Nothing is written when the project is invalid.
+func writeCompose(ctx context.Context, name string, project *composetypes.Project) error {
+	content, err := MarshalCompose(project)
+	if err != nil {
+		fmt.Printf("Error marshalling config: %v\n", err)
+		return err
+	}
+
+	err = ValidateCompose(ctx, name, content)
+	if err != nil {
+		fmt.Printf("Error validating config: %v\n", err)
+		return err
+	}
+
+	err = utils.WriteComposeFile(name, content)
+	if err != nil {
+		fmt.Printf("Error writing compose file: %v\n", err)
+		return err
+	}
+	return nil
+}
+
+// up runs the Docker Compose file of the stack and exposes it through the tunnel.
+func up(ctx context.Context, name, network string, expose []*types.ExposeRule) error {
+	err := EnsureNetwork(ctx, network)
+	if err != nil {
+		fmt.Printf("Error creating network: %v\n", err)
+		return err
+	}
+
+	compose, project, err := newStackCompose(ctx, name)
 	if err != nil {
 		fmt.Printf("Error creating compose: %v\n", err)
 		return err
 	}
+	err = resolveSecrets(project)
+	if err != nil {
+		fmt.Printf("Error resolving secrets: %v\n", err)
+		return err
+	}
 
-	// TODO: Expose the port with tunneling
+	err = compose.Up(ctx, project, api.UpOptions{})
+	if err != nil {
+		return err
+	}
 
-	return compose.Up(ctx, project, api.UpOptions{})
+	return ExposeRules(ctx, name, network, expose...)
 }
@@ -0,0 +1,161 @@
+package envme
+
+import (
+	"bytes"
+	"context"
+	"envme/lib/types"
+	"envme/lib/utils"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPreviewMatchesWritten(t *testing.T) {
+	setHome(t)
+	ctx := context.Background()
+	viper.Set("volume", []string{"data:/data", "/srv/config:/config:ro"})
+	viper.Set("mount-path", "/app")
+	t.Cleanup(func() {
+		viper.Set("volume", nil)
+		viper.Set("mount-path", nil)
+	})
+	publish := []string{"8080:80"}
+	rules, err := ParsePublishRules(publish)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertWritten := func(t *testing.T, name string, preview []byte) {
+		t.Helper()
+		written, err := utils.ReadComposeFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(preview, written) {
+			t.Errorf("preview:\n%s\nwritten:\n%s", preview, written)
+		}
+		for _, want := range []string{"target: /data", "source: /srv/config", `published: "8080"`} {
+			if !bytes.Contains(preview, []byte(want)) {
+				t.Errorf("preview has no %q:\n%s", want, preview)
+			}
+		}
+	}
+
+	t.Run("service", func(t *testing.T) {
+		preview, err := PreviewService(ctx, "api", "nginx", "envme", "redis", publish)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		entry, err := findCatalogEntry("redis")
+		if err != nil {
+			t.Fatal(err)
+		}
+		project, err := BuildService(ctx, serviceOptions("api", "nginx", "envme", entry, rules))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writeCompose(ctx, "api", project); err != nil {
+			t.Fatal(err)
+		}
+		assertWritten(t, "api", preview)
+	})
+
+	t.Run("development", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM scratch AS development\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		preview, err := PreviewDev(ctx, "web", dir, "(none)", "envme", publish)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		project, err := BuildDevelopment(ctx, developmentOptions("web", dir, "envme", nil, rules))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writeCompose(ctx, "web", project); err != nil {
+			t.Fatal(err)
+		}
+		assertWritten(t, "web", preview)
+	})
+
+	t.Run("sidecar", func(t *testing.T) {
+		preview, err := PreviewSidecar(ctx, "web", "admin", "adminer", "envme", publish)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		project, err := ReadCompose(ctx, "web")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := AddSidecar(ctx, project, sidecarOptions("web", "admin", "adminer", "envme", rules)); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeCompose(ctx, "web", project); err != nil {
+			t.Fatal(err)
+		}
+		assertWritten(t, "web", preview)
+	})
+}
+
+func TestCheckCreate(t *testing.T) {
+	setHome(t)
+	t.Cleanup(func() { viper.Set("force", nil) })
+	if err := utils.WriteComposeFile("api", []byte("services: {}\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteMetadata("edge", &types.Metadata{Kind: types.KindProxy}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		force   bool
+		wantErr bool
+	}{
+		{name: "web"},
+		{name: "Web", wantErr: true},
+		{name: "../web", wantErr: true},
+		{name: caDir, wantErr: true},
+		{name: templatesDir, force: true, wantErr: true},
+		{name: ProxyStack, force: true, wantErr: true},
+		{name: TunnelStack, force: true, wantErr: true},
+		{name: "edge", force: true, wantErr: true},
+		{name: "api", wantErr: true},
+		{name: "api", force: true},
+	}
+
+	for _, tt := range tests {
+		viper.Set("force", tt.force)
+		if err := checkCreate(tt.name); (err != nil) != tt.wantErr {
+			t.Errorf("checkCreate(%q) with force %v error = %v, wantErr %v", tt.name, tt.force, err, tt.wantErr)
+		}
+	}
+}
+
+func TestCreateDevKeepsDockerfile(t *testing.T) {
+	setHome(t)
+	viper.Set("mount-path", "/app")
+	t.Cleanup(func() { viper.Set("mount-path", nil) })
+	dir := t.TempDir()
+	dockerfile := []byte("FROM node AS development\n")
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), dockerfile, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CreateDev(context.Background(), "web", dir, "nextjs", "envme", nil); err == nil {
+		t.Fatal("the Dockerfile was replaced")
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "Dockerfile")); err != nil || !bytes.Equal(content, dockerfile) {
+		t.Errorf("Dockerfile = %q, %v", content, err)
+	}
+	for _, file := range []string{"docker-compose.yaml", utils.EnvFile, metadataFile} {
+		if utils.HasServiceFile("web", file) {
+			t.Errorf("%s written", file)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package envme
+
+import (
+	"envme/lib/templates"
+	"envme/lib/types"
+	"envme/lib/utils"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// templatesDir holds the user templates under ~/.envme
+const templatesDir = "templates"
+
+// TemplateDirs returns the directories holding user templates: ~/.envme/templates,
+// then .envme/templates of the project, which takes precedence.
+func TemplateDirs(projectDir string) ([]string, error) {
+	appDir, err := utils.GetAppDir()
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []string{filepath.Join(appDir, templatesDir)}
+	if projectDir != "" {
+		dirs = append(dirs, filepath.Join(projectDir, ".envme", templatesDir))
+	}
+	return dirs, nil
+}
+
+// LoadTemplates returns the builtin templates and the user templates visible from the project.
+func LoadTemplates(projectDir string) ([]*templates.Template, error) {
+	dirs, err := TemplateDirs(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	return templates.Load(dirs...)
+}
+
+// FindTemplate returns the template with the given name visible from the project.
+func FindTemplate(name, projectDir string) (*templates.Template, error) {
+	dirs, err := TemplateDirs(projectDir)
+	if err != nil {
+		return nil, err
+	}
+	return templates.Find(name, dirs...)
+}
+
+// DetectTemplate detects the framework of the project and returns it with the
+// matching template, which is nil when no template with that name exists.
+func DetectTemplate(dir string) (*templates.Detection, *templates.Template) {
+	detection := templates.Detect(dir)
+	if detection == nil {
+		return nil, nil
+	}
+
+	t, err := FindTemplate(detection.Template, dir)
+	if err != nil {
+		return detection, nil
+	}
+	return detection, t
+}
+
+// WriteTemplates prints the templates as a table.
+func WriteTemplates(w io.Writer, list []*templates.Template) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "NAME\tTITLE\tPORT\tPARAMS\tSOURCE")
+	for _, t := range list {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", t.Name, t.Title, t.Port, strings.Join(t.ParamNames(), ", "), t.Source)
+	}
+	return tw.Flush()
+}
+
+// mergeService applies the compose fragment of a template to the generated service.
+// Scalars of the fragment replace the generated ones, lists are appended.
+// Its ports are published with the --publish ones, see fragmentPublish.
+func mergeService(dst, src *types.Service) {
+	if src == nil {
+		return
+	}
+	if src.Image != "" {
+		dst.Image = src.Image
+	}
+	if src.Restart != "" {
+		dst.Restart = src.Restart
+	}
+	if len(src.Command) > 0 {
+		dst.Command = src.Command
+	}
+	if len(src.Entrypoint) > 0 {
+		dst.Entrypoint = src.Entrypoint
+	}
+	if src.User != "" {
+		dst.User = src.User
+	}
+	if src.WorkingDir != "" {
+		dst.WorkingDir = src.WorkingDir
+	}
+	if src.Healthcheck != nil {
+		dst.Healthcheck = src.Healthcheck
+	}
+	if src.Deploy != nil {
+		dst.Deploy = src.Deploy
+	}
+	if src.Build != nil && dst.Build != nil {
+		for key, value := range src.Build.Args {
+			if dst.Build.Args == nil {
+				dst.Build.Args = map[string]string{}
+			}
+			dst.Build.Args[key] = value
+		}
+	}
+	for key, value := range src.Labels {
+		if dst.Labels == nil {
+			dst.Labels = map[string]string{}
+		}
+		dst.Labels[key] = value
+	}
+	for name, dependency := range src.DependsOn {
+		if dst.DependsOn == nil {
+			dst.DependsOn = types.DependsOn{}
+		}
+		dst.DependsOn[name] = dependency
+	}
+	dst.Volumes = append(dst.Volumes, src.Volumes...)
+	dst.Environment = append(dst.Environment, src.Environment...)
+	dst.Expose = append(dst.Expose, src.Expose...)
+	dst.Profiles = append(dst.Profiles, src.Profiles...)
+	if src.Networks != nil {
+		networks := append(*dst.Networks, *src.Networks...)
+		dst.Networks = &networks
+	}
+	if src.ExtraHosts != nil {
+		hosts := append(*dst.ExtraHosts, *src.ExtraHosts...)
+		dst.ExtraHosts = &hosts
+	}
+}
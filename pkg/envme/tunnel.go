@@ -0,0 +1,401 @@
+package envme
+
+import (
+	"context"
+	"envme/lib/docker"
+	"envme/lib/types"
+	"envme/lib/utils"
+	"errors"
+	"fmt"
+	"github.com/docker/compose/v2/pkg/api"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	TunnelStack     = "cloudflared"
+	tunnelImage     = "cloudflare/cloudflared:latest"
+	tunnelConfigDir = "/etc/cloudflared"
+	tunnelConfig    = "config.yaml"
+	tunnelCreds     = "credentials.json"
+	catchAllService = "http_status:404"
+)
+
+// NewTunnelConfig returns an empty ingress config for the given tunnel.
+func NewTunnelConfig(tunnel string) *types.TunnelConfig {
+	return &types.TunnelConfig{
+		Tunnel:          tunnel,
+		CredentialsFile: filepath.Join(tunnelConfigDir, tunnelCreds),
+		Ingress:         []*types.IngressRule{{Service: catchAllService}},
+	}
+}
+
+// NewExposeRule validates the port and hostname of an expose rule.
+func NewExposeRule(port, hostname string) (*types.ExposeRule, error) {
+	p, err := strconv.Atoi(port)
+	if err != nil || p < 1 || p > 65535 {
+		return nil, fmt.Errorf("invalid port %q", port)
+	}
+	if hostname == "" || strings.ContainsAny(hostname, " /:") {
+		return nil, fmt.Errorf("invalid hostname %q", hostname)
+	}
+
+	return &types.ExposeRule{Port: p, Hostname: hostname}, nil
+}
+
+// ParseExposeRule parses an expose spec in the format <port>:<hostname>.
+func ParseExposeRule(spec string) (*types.ExposeRule, error) {
+	port, hostname, ok := strings.Cut(strings.TrimSpace(spec), ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid expose %q, expected <port>:<hostname>", spec)
+	}
+
+	rule, err := NewExposeRule(port, hostname)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expose %q: %w", spec, err)
+	}
+	return rule, nil
+}
+
+// ParseExposeRules parses every non-empty expose spec.
+func ParseExposeRules(specs []string) ([]*types.ExposeRule, error) {
+	var rules []*types.ExposeRule
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		}
+		rule, err := ParseExposeRule(spec)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// NewIngressRule returns the rule routing the hostname to the container of the given stack.
+func NewIngressRule(name string, rule *types.ExposeRule) *types.IngressRule {
+	return &types.IngressRule{
+		Hostname: rule.Hostname,
+		Service:  fmt.Sprintf("http://%s:%d", name, rule.Port),
+	}
+}
+
+// SaveExposeRules adds the rules to the metadata of the stack,
+// replacing any rule with the same hostname.
+func SaveExposeRules(name string, rules ...*types.ExposeRule) error {
+	metadata, err := ReadMetadata(name)
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		found := false
+		for _, r := range metadata.Expose {
+			if r.Hostname == rule.Hostname {
+				r.Port = rule.Port
+				found = true
+				break
+			}
+		}
+		if !found {
+			metadata.Expose = append(metadata.Expose, rule)
+		}
+	}
+
+	return WriteMetadata(name, metadata)
+}
+
+// MergeIngress adds the rules to the config, replacing any rule with the same
+// hostname, and keeps the catch-all rule last. It reports whether the config changed.
+func MergeIngress(config *types.TunnelConfig, rules ...*types.IngressRule) bool {
+	before := make([]types.IngressRule, 0, len(config.Ingress))
+	for _, r := range config.Ingress {
+		before = append(before, *r)
+	}
+
+	for _, rule := range rules {
+		found := false
+		for _, r := range config.Ingress {
+			if r.Hostname != "" && r.Hostname == rule.Hostname {
+				r.Service = rule.Service
+				found = true
+				break
+			}
+		}
+		if !found {
+			config.Ingress = append(config.Ingress, &types.IngressRule{Hostname: rule.Hostname, Service: rule.Service})
+		}
+	}
+
+	// Move the catch-all rule to the end, cloudflared requires it to be last.
+	// A catch-all written by the user is kept, the last one when there are several.
+	catchAll := &types.IngressRule{Service: catchAllService}
+	ingress := make([]*types.IngressRule, 0, len(config.Ingress))
+	for _, r := range config.Ingress {
+		if r.Hostname != "" {
+			ingress = append(ingress, r)
+		} else {
+			catchAll = r
+		}
+	}
+	config.Ingress = append(ingress, catchAll)
+
+	return !slices.EqualFunc(before, config.Ingress, func(a types.IngressRule, b *types.IngressRule) bool {
+		return a == *b
+	})
+}
+
+// RemoveIngress drops every rule routing to the container of the given stack
+// or using one of the given hostnames. It reports whether the config changed.
+func RemoveIngress(config *types.TunnelConfig, name string, hostnames ...string) bool {
+	prefix := "http://" + name + ":"
+	ingress := make([]*types.IngressRule, 0, len(config.Ingress))
+	for _, r := range config.Ingress {
+		if r.Hostname != "" && (strings.HasPrefix(r.Service, prefix) || slices.Contains(hostnames, r.Hostname)) {
+			continue
+		}
+		ingress = append(ingress, r)
+	}
+
+	changed := len(ingress) != len(config.Ingress)
+	config.Ingress = ingress
+	return changed
+}
+
+// ReadTunnelConfig loads the ingress config of the tunnel stack,
+// returning a fresh one when it has not been generated yet.
+func ReadTunnelConfig() (*types.TunnelConfig, error) {
+	content, err := utils.ReadServiceFile(TunnelStack, tunnelConfig)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewTunnelConfig(viper.GetString("tunnel.name")), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	config := &types.TunnelConfig{}
+	if err := yaml.Unmarshal(content, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func WriteTunnelConfig(config *types.TunnelConfig) error {
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteServiceFile(TunnelStack, tunnelConfig, content, 0644)
+}
+
+// Expose routes the hostname to the port of the given stack through the envme tunnel.
+func Expose(ctx context.Context, name, port, hostname, network string) error {
+	if !utils.HasService(name) {
+		return fmt.Errorf("service %q not found", name)
+	}
+	metadata, err := ReadMetadata(name)
+	if err != nil {
+		return err
+	}
+	if IsManaged(name, metadata) {
+		return fmt.Errorf("%s is managed by envme and cannot be exposed", name)
+	}
+	rule, err := NewExposeRule(port, hostname)
+	if err != nil {
+		return err
+	}
+
+	return ExposeRules(ctx, name, network, rule)
+}
+
+// ExposeRules records the rules in the metadata of the stack and applies them to the tunnel ingress.
+func ExposeRules(ctx context.Context, name, network string, rules ...*types.ExposeRule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	err := SaveExposeRules(name, rules...)
+	if err != nil {
+		fmt.Printf("Error saving expose rules: %v\n", err)
+		return err
+	}
+
+	ingress := make([]*types.IngressRule, 0, len(rules))
+	for _, rule := range rules {
+		ingress = append(ingress, NewIngressRule(name, rule))
+	}
+	return ApplyIngress(ctx, network, ingress...)
+}
+
+// ApplyIngress merges the rules into the tunnel config and restarts the tunnel
+// when something changed.
+func ApplyIngress(ctx context.Context, network string, rules ...*types.IngressRule) error {
+	config, err := ReadTunnelConfig()
+	if err != nil {
+		fmt.Printf("Error reading tunnel config: %v\n", err)
+		return err
+	}
+
+	changed := MergeIngress(config, rules...)
+	if changed {
+		err = WriteTunnelConfig(config)
+		if err != nil {
+			fmt.Printf("Error writing tunnel config: %v\n", err)
+			return err
+		}
+	}
+
+	for _, rule := range rules {
+		fmt.Printf("Exposing %s on https://%s\n", rule.Service, rule.Hostname)
+	}
+
+	return upTunnel(ctx, network, changed)
+}
+
+// Unexpose removes the tunnel ingress of the given stack, including the hostnames
+// recorded in its metadata, and restarts the tunnel when it is running.
+func Unexpose(ctx context.Context, name string) error {
+	if !utils.HasService(TunnelStack) {
+		return nil
+	}
+
+	config, err := ReadTunnelConfig()
+	if err != nil {
+		fmt.Printf("Error reading tunnel config: %v\n", err)
+		return err
+	}
+
+	metadata, err := ReadMetadata(name)
+	if err != nil {
+		return err
+	}
+	var hostnames []string
+	for _, rule := range metadata.Expose {
+		hostnames = append(hostnames, rule.Hostname)
+	}
+
+	if !RemoveIngress(config, name, hostnames...) {
+		return nil
+	}
+	err = WriteTunnelConfig(config)
+	if err != nil {
+		fmt.Printf("Error writing tunnel config: %v\n", err)
+		return err
+	}
+
+	cli, err := docker.NewClient()
+	if err != nil {
+		return err
+	}
+	containers, err := docker.ListContainers(ctx, cli, TunnelStack)
+	if err != nil {
+		return err
+	}
+	for _, c := range containers {
+		if c.State == types.StateRunning {
+			return Restart(ctx, TunnelStack)
+		}
+	}
+	return nil
+}
+
+// upTunnel writes the compose file of the tunnel stack and starts it,
+// restarting a running tunnel so it picks up the new ingress rules.
+func upTunnel(ctx context.Context, network string, restart bool) error {
+	err := ensureTunnelCredentials()
+	if err != nil {
+		return err
+	}
+
+	dir, err := utils.GetServiceDir(TunnelStack)
+	if err != nil {
+		return err
+	}
+
+	project, err := toProject(ctx, TunnelStack, newCompose(TunnelStack, network, &types.Service{
+		ContainerName: TunnelStack,
+		Image:         tunnelImage,
+		Restart:       "unless-stopped",
+		Command:       types.ShellCommand{"tunnel", "--no-autoupdate", "--config", filepath.Join(tunnelConfigDir, tunnelConfig), "run"},
+		Volumes:       []string{dir + ":" + tunnelConfigDir},
+		Networks:      &[]string{network},
+	}, nil))
+	if err != nil {
+		return err
+	}
+	err = writeCompose(ctx, TunnelStack, project)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := ReadMetadata(TunnelStack)
+	if err != nil {
+		return err
+	}
+	if metadata.Kind == "" {
+		metadata = NewMetadata(types.KindTunnel)
+		metadata.Image = tunnelImage
+		err = WriteMetadata(TunnelStack, metadata)
+		if err != nil {
+			fmt.Printf("Error writing metadata: %v\n", err)
+			return err
+		}
+	}
+
+	err = EnsureNetwork(ctx, network)
+	if err != nil {
+		fmt.Printf("Error creating network: %v\n", err)
+		return err
+	}
+
+	compose, project, err := docker.NewCompose(ctx, TunnelStack)
+	if err != nil {
+		fmt.Printf("Error creating compose: %v\n", err)
+		return err
+	}
+
+	err = compose.Up(ctx, project, api.UpOptions{})
+	if err != nil || !restart {
+		return err
+	}
+
+	return compose.Restart(ctx, project.Name, api.RestartOptions{Project: project})
+}
+
+// ensureTunnelCredentials copies the credentials of the configured tunnel
+// into the tunnel stack unless they are already there.
+func ensureTunnelCredentials() error {
+	dir, err := utils.GetServiceDir(TunnelStack)
+	if err != nil {
+		return err
+	}
+
+	target := filepath.Join(dir, tunnelCreds)
+	if _, err := os.Stat(target); err == nil {
+		return nil
+	}
+
+	source := viper.GetString("tunnel.credentials-file")
+	if source == "" {
+		return fmt.Errorf("no tunnel credentials found, run `cloudflared tunnel create %s` and set tunnel.credentials-file in the envme config or copy the file to %s", viper.GetString("tunnel.name"), target)
+	}
+	source, err = utils.GetAbsPath(source)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+
+	// The cloudflared image runs as a non-root user and needs to read the file
+	return os.WriteFile(target, content, 0644)
+}
@@ -0,0 +1,179 @@
+package envme
+
+import (
+	"envme/lib/types"
+	"reflect"
+	"testing"
+)
+
+func ingress(rules ...string) []*types.IngressRule {
+	list := make([]*types.IngressRule, 0, len(rules)/2)
+	for i := 0; i < len(rules); i += 2 {
+		list = append(list, &types.IngressRule{Hostname: rules[i], Service: rules[i+1]})
+	}
+	return list
+}
+
+func TestNewTunnelConfig(t *testing.T) {
+	config := NewTunnelConfig("envme")
+	if config.Tunnel != "envme" {
+		t.Errorf("tunnel = %q, want envme", config.Tunnel)
+	}
+	if config.CredentialsFile != "/etc/cloudflared/credentials.json" {
+		t.Errorf("credentials-file = %q", config.CredentialsFile)
+	}
+	if want := ingress("", catchAllService); !reflect.DeepEqual(config.Ingress, want) {
+		t.Errorf("ingress = %v, want only the catch-all", config.Ingress)
+	}
+}
+
+func TestMergeIngress(t *testing.T) {
+	tests := []struct {
+		name    string
+		ingress []*types.IngressRule
+		rules   []*types.IngressRule
+		want    []*types.IngressRule
+		changed bool
+	}{
+		{
+			name:    "add to empty config",
+			ingress: ingress("", catchAllService),
+			rules:   ingress("api.example.com", "http://api:3000"),
+			want:    ingress("api.example.com", "http://api:3000", "", catchAllService),
+			changed: true,
+		},
+		{
+			name:    "re-merge is idempotent",
+			ingress: ingress("api.example.com", "http://api:3000", "", catchAllService),
+			rules:   ingress("api.example.com", "http://api:3000"),
+			want:    ingress("api.example.com", "http://api:3000", "", catchAllService),
+			changed: false,
+		},
+		{
+			name:    "replace the service of a hostname",
+			ingress: ingress("api.example.com", "http://api:3000", "", catchAllService),
+			rules:   ingress("api.example.com", "http://web:8080"),
+			want:    ingress("api.example.com", "http://web:8080", "", catchAllService),
+			changed: true,
+		},
+		{
+			name:    "catch-all stays last",
+			ingress: ingress("", catchAllService, "api.example.com", "http://api:3000"),
+			rules:   ingress("web.example.com", "http://web:8080"),
+			want:    ingress("api.example.com", "http://api:3000", "web.example.com", "http://web:8080", "", catchAllService),
+			changed: true,
+		},
+		{
+			name:    "catch-all added when missing",
+			ingress: ingress("api.example.com", "http://api:3000"),
+			rules:   ingress("api.example.com", "http://api:3000"),
+			want:    ingress("api.example.com", "http://api:3000", "", catchAllService),
+			changed: true,
+		},
+		{
+			name:    "custom catch-all kept",
+			ingress: ingress("api.example.com", "http://api:3000", "", "http://fallback:8080"),
+			rules:   ingress("web.example.com", "http://web:8080"),
+			want:    ingress("api.example.com", "http://api:3000", "web.example.com", "http://web:8080", "", "http://fallback:8080"),
+			changed: true,
+		},
+		{
+			name:    "custom catch-all unchanged",
+			ingress: ingress("api.example.com", "http://api:3000", "", "http://fallback:8080"),
+			rules:   ingress("api.example.com", "http://api:3000"),
+			want:    ingress("api.example.com", "http://api:3000", "", "http://fallback:8080"),
+			changed: false,
+		},
+		{
+			name:    "custom catch-all moved last",
+			ingress: ingress("", "http://fallback:8080", "api.example.com", "http://api:3000"),
+			rules:   ingress("api.example.com", "http://api:3000"),
+			want:    ingress("api.example.com", "http://api:3000", "", "http://fallback:8080"),
+			changed: true,
+		},
+		{
+			name:    "last of several catch-alls kept",
+			ingress: ingress("", catchAllService, "api.example.com", "http://api:3000", "", "http://fallback:8080"),
+			rules:   ingress("api.example.com", "http://api:3000"),
+			want:    ingress("api.example.com", "http://api:3000", "", "http://fallback:8080"),
+			changed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &types.TunnelConfig{Tunnel: "envme", Ingress: tt.ingress}
+			changed := MergeIngress(config, tt.rules...)
+			if changed != tt.changed {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+			if !reflect.DeepEqual(config.Ingress, tt.want) {
+				t.Errorf("ingress = %v, want %v", config.Ingress, tt.want)
+			}
+
+			// Merging the same rules again never changes the config
+			if MergeIngress(config, tt.rules...) {
+				t.Errorf("second merge reported a change")
+			}
+			if !reflect.DeepEqual(config.Ingress, tt.want) {
+				t.Errorf("ingress after second merge = %v, want %v", config.Ingress, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveIngress(t *testing.T) {
+	tests := []struct {
+		name      string
+		stack     string
+		hostnames []string
+		want      []*types.IngressRule
+		changed   bool
+	}{
+		{
+			name:    "by stack prefix",
+			stack:   "api",
+			want:    ingress("web.example.com", "http://web:8080", "other.example.com", "http://api-v2:3000", "", catchAllService),
+			changed: true,
+		},
+		{
+			name:      "by hostname",
+			stack:     "none",
+			hostnames: []string{"web.example.com"},
+			want:      ingress("api.example.com", "http://api:3000", "admin.example.com", "http://api:9000", "other.example.com", "http://api-v2:3000", "", catchAllService),
+			changed:   true,
+		},
+		{
+			name:      "by prefix and hostname",
+			stack:     "api",
+			hostnames: []string{"other.example.com"},
+			want:      ingress("web.example.com", "http://web:8080", "", catchAllService),
+			changed:   true,
+		},
+		{
+			name:    "nothing to remove",
+			stack:   "db",
+			want:    ingress("api.example.com", "http://api:3000", "web.example.com", "http://web:8080", "admin.example.com", "http://api:9000", "other.example.com", "http://api-v2:3000", "", catchAllService),
+			changed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &types.TunnelConfig{Tunnel: "envme", Ingress: ingress(
+				"api.example.com", "http://api:3000",
+				"web.example.com", "http://web:8080",
+				"admin.example.com", "http://api:9000",
+				"other.example.com", "http://api-v2:3000",
+				"", catchAllService,
+			)}
+			changed := RemoveIngress(config, tt.stack, tt.hostnames...)
+			if changed != tt.changed {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+			if !reflect.DeepEqual(config.Ingress, tt.want) {
+				t.Errorf("ingress = %v, want %v", config.Ingress, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package envme
+
+import (
+	"envme/lib/utils"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// dependencyDirs are kept in anonymous volumes so the bind-mounted source does
+// not hide the dependencies installed in the image.
+var dependencyDirs = map[string]string{
+	"package.json":  "node_modules",
+	"composer.json": "vendor",
+}
+
+// DevVolumes returns the volumes of a development environment: the project
+// directory mounted at mountPath and an anonymous volume per dependency directory.
+func DevVolumes(dir, mountPath string) []string {
+	volumes := []string{dir + ":" + mountPath}
+	for _, manifest := range []string{"package.json", "composer.json"} {
+		if _, err := os.Stat(filepath.Join(dir, manifest)); err == nil {
+			volumes = append(volumes, path.Join(mountPath, dependencyDirs[manifest]))
+		}
+	}
+	return volumes
+}
+
+// ParseVolumes validates volume specs ([<source>:]<target>[:<mode>]), resolving
+// relative host paths against the current directory since compose resolves them
+// against the stack directory. It also returns the named volumes used.
+func ParseVolumes(specs []string) ([]string, []string, error) {
+	var volumes, named []string
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		parts := strings.Split(spec, ":")
+		if len(parts) > 3 {
+			return nil, nil, fmt.Errorf("invalid volume %q, expected [<source>:]<target>[:<mode>]", spec)
+		}
+		if len(parts) == 1 {
+			if !path.IsAbs(parts[0]) {
+				return nil, nil, fmt.Errorf("invalid volume %q, the target must be an absolute path", spec)
+			}
+			volumes = append(volumes, spec)
+			continue
+		}
+
+		source, target := parts[0], parts[1]
+		if !path.IsAbs(target) {
+			return nil, nil, fmt.Errorf("invalid volume %q, the target must be an absolute path", spec)
+		}
+		if len(parts) == 3 && parts[2] != "ro" && parts[2] != "rw" {
+			return nil, nil, fmt.Errorf("invalid volume %q, the mode must be ro or rw", spec)
+		}
+
+		if strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~") || strings.HasPrefix(source, "/") {
+			abs, err := utils.GetAbsPath(source)
+			if err != nil {
+				return nil, nil, err
+			}
+			parts[0] = filepath.Clean(abs)
+		} else {
+			named = append(named, source)
+		}
+		volumes = append(volumes, strings.Join(parts, ":"))
+	}
+	return volumes, named, nil
+}